@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// Credential kinds a consumer can authenticate with
+const (
+	KindAPIKey = "key"
+	KindJWT    = "jwt"
+)
+
+// Consumer identifies an authenticated API client. IDs are unique per Kind:
+// an API key ID and a token subject may coincide.
+type Consumer struct {
+	ID     string   `json:"id"`
+	Kind   string   `json:"kind"`
+	Name   string   `json:"name"`
+	Tier   string   `json:"tier"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// HasScope reports whether the consumer was granted scope
+func (c *Consumer) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithConsumer returns a copy of ctx carrying the consumer
+func WithConsumer(ctx context.Context, consumer *Consumer) context.Context {
+	return context.WithValue(ctx, contextKey{}, consumer)
+}
+
+// ConsumerFromContext returns the consumer attached to ctx, if any
+func ConsumerFromContext(ctx context.Context) (*Consumer, bool) {
+	consumer, ok := ctx.Value(contextKey{}).(*Consumer)
+	return consumer, ok
+}
+
+// KeyStore validates API keys against their stored SHA-256 hashes
+type KeyStore struct {
+	consumers map[string]*Consumer
+}
+
+// NewKeyStore builds a key store from configured keys and the optional keys file
+func NewKeyStore(cfg config.AuthConfig) (*KeyStore, error) {
+	entries := append([]config.APIKeyConfig{}, cfg.APIKeys...)
+	if cfg.KeysFile != "" {
+		fileEntries, err := loadKeysFile(cfg.KeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load API keys file: %v", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	ks := &KeyStore{consumers: make(map[string]*Consumer, len(entries))}
+	for i, entry := range entries {
+		hash := strings.ToLower(strings.TrimPrefix(entry.Hash, "sha256:"))
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("api key %d (%s) must have a hex-encoded SHA-256 hash", i, entry.ID)
+		}
+		if entry.ID == "" {
+			return nil, fmt.Errorf("api key %d is missing an id", i)
+		}
+		if _, exists := ks.consumers[hash]; exists {
+			return nil, fmt.Errorf("api key %s duplicates another key", entry.ID)
+		}
+		ks.consumers[hash] = &Consumer{
+			ID:     entry.ID,
+			Kind:   KindAPIKey,
+			Name:   entry.Name,
+			Tier:   entry.Tier,
+			Scopes: entry.Scopes,
+		}
+	}
+	return ks, nil
+}
+
+// Lookup returns the consumer owning key
+func (ks *KeyStore) Lookup(key string) (*Consumer, bool) {
+	consumer, ok := ks.consumers[HashKey(key)]
+	return consumer, ok
+}
+
+// HashKey returns the hex-encoded SHA-256 hash stored for a raw API key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadKeysFile reads a JSON array of API key entries
+func loadKeysFile(path string) ([]config.APIKeyConfig, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []config.APIKeyConfig
+	if err := json.Unmarshal(bytes, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ErrNoCredentials is returned when a request carries neither an API key nor a bearer token
+var ErrNoCredentials = errors.New("no credentials provided")
+
+// Authenticator resolves the consumer for a request from an API key or a JWT bearer token
+type Authenticator struct {
+	keys       *KeyStore
+	jwt        *JWTVerifier
+	headerName string
+	queryParam string
+}
+
+// NewAuthenticator builds an authenticator from configuration. API keys are
+// checked when any are configured and bearer tokens when JWT is enabled.
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{
+		headerName: cfg.HeaderName,
+		queryParam: cfg.QueryParam,
+	}
+
+	if len(cfg.APIKeys) > 0 || cfg.KeysFile != "" {
+		keys, err := NewKeyStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+	if cfg.JWT.Enabled {
+		verifier, err := NewJWTVerifier(cfg.JWT)
+		if err != nil {
+			return nil, err
+		}
+		a.jwt = verifier
+	}
+	return a, nil
+}
+
+// Schemes returns the WWW-Authenticate challenges for the enabled credential types
+func (a *Authenticator) Schemes() []string {
+	var schemes []string
+	if a.keys != nil {
+		schemes = append(schemes, `ApiKey realm="weather-api"`)
+	}
+	if a.jwt != nil {
+		schemes = append(schemes, `Bearer realm="weather-api"`)
+	}
+	return schemes
+}
+
+// Authenticate returns the consumer identified by the request's credentials.
+// A bearer token takes precedence over an API key when both are present.
+func (a *Authenticator) Authenticate(r *http.Request) (*Consumer, error) {
+	if a.jwt != nil {
+		if token, ok := bearerToken(r); ok {
+			consumer, err := a.jwt.Verify(token)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bearer token: %v", err)
+			}
+			return consumer, nil
+		}
+	}
+
+	if a.keys != nil {
+		var key string
+		if a.headerName != "" {
+			key = r.Header.Get(a.headerName)
+		}
+		if key == "" && a.queryParam != "" {
+			key = r.URL.Query().Get(a.queryParam)
+		}
+		if key != "" {
+			consumer, ok := a.keys.Lookup(key)
+			if !ok {
+				return nil, errors.New("invalid API key")
+			}
+			return consumer, nil
+		}
+	}
+
+	return nil, ErrNoCredentials
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
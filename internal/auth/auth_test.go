@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+func TestNewKeyStore(t *testing.T) {
+	hash := HashKey("secret")
+
+	tests := []struct {
+		name    string
+		keys    []config.APIKeyConfig
+		wantErr string
+	}{
+		{"plain hash", []config.APIKeyConfig{{ID: "portal", Hash: hash}}, ""},
+		{"prefixed upper case hash", []config.APIKeyConfig{{ID: "portal", Hash: "sha256:" + strings.ToUpper(hash)}}, ""},
+		{"short hash", []config.APIKeyConfig{{ID: "portal", Hash: hash[:32]}}, "must have a hex-encoded SHA-256 hash"},
+		{"raw key", []config.APIKeyConfig{{ID: "portal", Hash: "secret"}}, "must have a hex-encoded SHA-256 hash"},
+		{"missing id", []config.APIKeyConfig{{Hash: hash}}, "missing an id"},
+		{"duplicate", []config.APIKeyConfig{{ID: "a", Hash: hash}, {ID: "b", Hash: "sha256:" + hash}}, "duplicates another key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyStore(config.AuthConfig{APIKeys: tt.keys})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestKeyStoreLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	file := `[{"id": "mobile", "name": "Mobile app", "hash": "` + HashKey("from-file") + `", "tier": "premium"}]`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("write keys file: %v", err)
+	}
+
+	ks, err := NewKeyStore(config.AuthConfig{
+		APIKeys:  []config.APIKeyConfig{{ID: "portal", Name: "Portal", Hash: HashKey("from-config"), Tier: "standard", Scopes: []string{"admin"}}},
+		KeysFile: path,
+	})
+	if err != nil {
+		t.Fatalf("NewKeyStore: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		wantID   string
+		wantTier string
+	}{
+		{"from-config", "portal", "standard"},
+		{"from-file", "mobile", "premium"},
+		{"unknown", "", ""},
+		{HashKey("from-config"), "", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		consumer, ok := ks.Lookup(tt.key)
+		if ok != (tt.wantID != "") {
+			t.Errorf("Lookup(%q) ok = %v, want %v", tt.key, ok, tt.wantID != "")
+			continue
+		}
+		if ok && (consumer.ID != tt.wantID || consumer.Tier != tt.wantTier) {
+			t.Errorf("Lookup(%q) = %s/%s, want %s/%s", tt.key, consumer.ID, consumer.Tier, tt.wantID, tt.wantTier)
+		}
+	}
+
+	if consumer, _ := ks.Lookup("from-config"); !consumer.HasScope("admin") || consumer.HasScope("history:read") {
+		t.Errorf("scopes = %v, want [admin]", consumer.Scopes)
+	}
+}
+
+func TestNewKeyStoreMissingFile(t *testing.T) {
+	_, err := NewKeyStore(config.AuthConfig{KeysFile: filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil {
+		t.Fatal("expected an error for a missing keys file")
+	}
+}
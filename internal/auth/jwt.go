@@ -0,0 +1,379 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// JWTVerifier validates bearer tokens and maps their claims to consumers
+type JWTVerifier struct {
+	algorithms map[string]bool
+	hmacSecret []byte
+	defaultKey crypto.PublicKey
+	keys       map[string]crypto.PublicKey
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	scopeMap   map[string][]string
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims holds the registered and custom claims read from a token
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Name      string          `json:"name"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Scope     string          `json:"scope"`
+	Scopes    []string        `json:"scp"`
+	Tier      string          `json:"tier"`
+}
+
+// NewJWTVerifier creates a verifier from configuration, loading keys from the
+// configured PEM public key and JWKS files
+func NewJWTVerifier(cfg config.JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		algorithms: make(map[string]bool),
+		keys:       make(map[string]crypto.PublicKey),
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		leeway:     time.Duration(cfg.Leeway) * time.Second,
+		scopeMap:   cfg.ScopeMap,
+	}
+
+	for _, alg := range cfg.Algorithms {
+		switch alg {
+		case AlgHS256, AlgRS256, AlgES256:
+			v.algorithms[alg] = true
+		default:
+			return nil, fmt.Errorf("unsupported jwt algorithm %q", alg)
+		}
+	}
+
+	if cfg.HMACSecret != "" {
+		v.hmacSecret = []byte(cfg.HMACSecret)
+	}
+	if v.algorithms[AlgHS256] && v.hmacSecret == nil {
+		return nil, fmt.Errorf("HS256 is allowed but no hmac secret is configured")
+	}
+
+	if cfg.PublicKeyFile != "" {
+		key, err := loadPEMPublicKey(cfg.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwt public key: %v", err)
+		}
+		v.defaultKey = key
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load jwks: %v", err)
+		}
+		v.keys = keys
+	}
+
+	return v, nil
+}
+
+// Verify checks the token signature and claims and returns the consumer it identifies
+func (v *JWTVerifier) Verify(token string) (*Consumer, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %v", err)
+	}
+	if !v.algorithms[header.Alg] {
+		return nil, fmt.Errorf("token algorithm %q is not allowed", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid token signature encoding")
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %v", err)
+	}
+	if err := v.validateClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return &Consumer{
+		ID:     claims.Subject,
+		Kind:   KindJWT,
+		Name:   claims.Name,
+		Tier:   claims.Tier,
+		Scopes: v.mapScopes(claims),
+	}, nil
+}
+
+// verifySignature checks the signature over signingInput with the key for the token's algorithm
+func (v *JWTVerifier) verifySignature(header jwtHeader, signingInput string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signingInput))
+
+	switch header.Alg {
+	case AlgHS256:
+		mac := hmac.New(sha256.New, v.hmacSecret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	case AlgRS256:
+		key, ok := v.publicKey(header.Kid).(*rsa.PublicKey)
+		if !ok {
+			return errors.New("no RSA key available to verify token")
+		}
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	case AlgES256:
+		key, ok := v.publicKey(header.Kid).(*ecdsa.PublicKey)
+		if !ok || key.Curve != elliptic.P256() {
+			return errors.New("no P-256 key available to verify token")
+		}
+		if len(signature) != 64 {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %q is not supported", header.Alg)
+}
+
+// publicKey returns the JWKS key with the given id, falling back to the PEM key
+func (v *JWTVerifier) publicKey(kid string) crypto.PublicKey {
+	if key, ok := v.keys[kid]; ok {
+		return key
+	}
+	if v.defaultKey != nil {
+		return v.defaultKey
+	}
+	// A JWKS with a single key is used for tokens without a matching kid
+	if len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// validateClaims checks expiry, not-before, issuer and audience
+func (v *JWTVerifier) validateClaims(claims jwtClaims, now time.Time) error {
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(v.leeway)) {
+		return errors.New("token has expired")
+	}
+	if claims.NotBefore != nil && now.Add(v.leeway).Before(unixTime(*claims.NotBefore)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return errors.New("token issuer is not trusted")
+	}
+	if v.audience != "" && !audienceContains(claims.Audience, v.audience) {
+		return errors.New("token audience does not include this service")
+	}
+	if claims.Subject == "" {
+		return errors.New("token has no subject")
+	}
+	return nil
+}
+
+// mapScopes translates token scopes through the configured scope map; scopes
+// without a mapping are kept as-is
+func (v *JWTVerifier) mapScopes(claims jwtClaims) []string {
+	raw := append(strings.Fields(claims.Scope), claims.Scopes...)
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, scope := range raw {
+		mapped, ok := v.scopeMap[scope]
+		if !ok {
+			mapped = []string{scope}
+		}
+		for _, s := range mapped {
+			if !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+// audienceContains reports whether the aud claim, a string or array, contains audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	if len(raw) == 0 {
+		return false
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		for _, aud := range multiple {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, out)
+}
+
+// loadPEMPublicKey reads an RSA or ECDSA public key in PKIX PEM form
+func loadPEMPublicKey(path string) (crypto.PublicKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(bytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// jwk is a single JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// loadJWKS reads RSA and P-256 EC keys from a local JWKS file
+func loadJWKS(path string) (map[string]crypto.PublicKey, error) {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(bytes, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for i, k := range set.Keys {
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%s): %v", i, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.New("invalid modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.New("invalid x coordinate")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, errors.New("invalid y coordinate")
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("point is not on the P-256 curve")
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+const testHMACSecret = "test-secret-with-enough-entropy"
+
+// testKeys are generated once; RSA key generation is slow
+var testKeys = struct {
+	rsa1, rsa2 *rsa.PrivateKey
+	ec         *ecdsa.PrivateKey
+}{
+	rsa1: mustRSAKey(),
+	rsa2: mustRSAKey(),
+	ec:   mustECKey(),
+}
+
+func mustRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustECKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// signToken builds a compact JWT with the given header fields and claims,
+// signed with key: a []byte HMAC secret, *rsa.PrivateKey or *ecdsa.PrivateKey
+func signToken(t *testing.T, alg, kid string, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	input := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign RS256: %v", err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("sign ES256: %v", err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case nil:
+	default:
+		t.Fatalf("unsupported signing key %T", key)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	raw, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encode segment: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// validClaims returns claims accepted by newTestVerifier
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub":   "dashboard",
+		"name":  "Dashboard",
+		"iss":   "https://issuer.example",
+		"aud":   []string{"weather-api", "other"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"nbf":   now.Add(-time.Minute).Unix(),
+		"scope": "read:weather admin",
+		"tier":  "premium",
+	}
+}
+
+// newTestVerifier accepts all three algorithms: HS256 with testHMACSecret,
+// RS256 through a JWKS holding rsa1 and rsa2, and ES256 through a PEM key
+func newTestVerifier(t *testing.T) *JWTVerifier {
+	t.Helper()
+	dir := t.TempDir()
+
+	der, err := x509.MarshalPKIXPublicKey(&testKeys.ec.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal EC key: %v", err)
+	}
+	pemFile := filepath.Join(dir, "ec.pem")
+	if err := os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	jwksFile := filepath.Join(dir, "jwks.json")
+	writeJWKS(t, jwksFile, map[string]*rsa.PublicKey{
+		"rsa-1": &testKeys.rsa1.PublicKey,
+		"rsa-2": &testKeys.rsa2.PublicKey,
+	})
+
+	v, err := NewJWTVerifier(config.JWTConfig{
+		Algorithms:    []string{AlgHS256, AlgRS256, AlgES256},
+		HMACSecret:    testHMACSecret,
+		PublicKeyFile: pemFile,
+		JWKSFile:      jwksFile,
+		Issuer:        "https://issuer.example",
+		Audience:      "weather-api",
+		Leeway:        30,
+		ScopeMap:      map[string][]string{"read:weather": {"weather", "forecast"}},
+	})
+	if err != nil {
+		t.Fatalf("NewJWTVerifier: %v", err)
+	}
+	return v
+}
+
+func writeJWKS(t *testing.T, path string, keys map[string]*rsa.PublicKey) {
+	t.Helper()
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	raw, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyAcceptsSignedTokens(t *testing.T) {
+	v := newTestVerifier(t)
+
+	tests := []struct {
+		name string
+		alg  string
+		kid  string
+		key  interface{}
+	}{
+		{"HS256", AlgHS256, "", []byte(testHMACSecret)},
+		{"RS256 first kid", AlgRS256, "rsa-1", testKeys.rsa1},
+		{"RS256 second kid", AlgRS256, "rsa-2", testKeys.rsa2},
+		{"ES256 PEM key", AlgES256, "", testKeys.ec},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer, err := v.Verify(signToken(t, tt.alg, tt.kid, validClaims(), tt.key))
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if consumer.ID != "dashboard" || consumer.Name != "Dashboard" || consumer.Tier != "premium" {
+				t.Errorf("consumer = %+v", consumer)
+			}
+			want := []string{"weather", "forecast", "admin"}
+			if strings.Join(consumer.Scopes, ",") != strings.Join(want, ",") {
+				t.Errorf("scopes = %v, want %v", consumer.Scopes, want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsTokens(t *testing.T) {
+	v := newTestVerifier(t)
+	now := time.Now()
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	tampered := func() string {
+		token := signToken(t, AlgHS256, "", validClaims(), []byte(testHMACSecret))
+		parts := strings.Split(token, ".")
+		parts[1] = encodeSegment(t, with("sub", "someone-else"))
+		return strings.Join(parts, ".")
+	}
+
+	// Public key bytes as an HMAC secret: the classic algorithm confusion attack
+	der, err := x509.MarshalPKIXPublicKey(&testKeys.rsa1.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsOnly, err := NewJWTVerifier(config.JWTConfig{
+		Algorithms: []string{AlgRS256},
+		HMACSecret: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		wantErr  string
+	}{
+		{"malformed", v, "not-a-jwt", "malformed token"},
+		{"expired", v, signToken(t, AlgHS256, "", with("exp", now.Add(-time.Minute).Unix()), []byte(testHMACSecret)), "expired"},
+		{"no expiry", v, signToken(t, AlgHS256, "", with("exp", nil), []byte(testHMACSecret)), "no expiry"},
+		{"not yet valid", v, signToken(t, AlgHS256, "", with("nbf", now.Add(time.Minute).Unix()), []byte(testHMACSecret)), "not valid yet"},
+		{"alg none", v, signToken(t, "none", "", validClaims(), nil), `"none" is not allowed`},
+		{"alg not allowed", rsOnly, signToken(t, AlgHS256, "", validClaims(), []byte(rsOnly.hmacSecret)), `"HS256" is not allowed`},
+		{"unknown alg", v, signToken(t, "RS512", "", validClaims(), testKeys.rsa1), `"RS512" is not allowed`},
+		{"HS256 wrong secret", v, signToken(t, AlgHS256, "", validClaims(), []byte("wrong")), "invalid token signature"},
+		{"tampered claims", v, tampered(), "invalid token signature"},
+		{"RS256 wrong kid", v, signToken(t, AlgRS256, "rsa-1", validClaims(), testKeys.rsa2), "invalid token signature"},
+		{"RS256 unknown kid", v, signToken(t, AlgRS256, "rsa-3", validClaims(), testKeys.rsa1), "no RSA key"},
+		{"ES256 wrong key", v, signToken(t, AlgES256, "", validClaims(), mustECKey()), "invalid token signature"},
+		{"ES256 with RSA kid", v, signToken(t, AlgES256, "rsa-1", validClaims(), testKeys.ec), "no P-256 key"},
+		{"wrong issuer", v, signToken(t, AlgHS256, "", with("iss", "https://evil.example"), []byte(testHMACSecret)), "issuer"},
+		{"wrong audience", v, signToken(t, AlgHS256, "", with("aud", "other"), []byte(testHMACSecret)), "audience"},
+		{"no subject", v, signToken(t, AlgHS256, "", with("sub", nil), []byte(testHMACSecret)), "no subject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer, err := tt.verifier.Verify(tt.token)
+			if err == nil {
+				t.Fatalf("Verify accepted the token as %+v", consumer)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyLeeway(t *testing.T) {
+	v := newTestVerifier(t)
+	now := time.Now()
+
+	expired := validClaims()
+	expired["exp"] = now.Add(-10 * time.Second).Unix()
+	if _, err := v.Verify(signToken(t, AlgHS256, "", expired, []byte(testHMACSecret))); err != nil {
+		t.Errorf("token expired within the leeway was rejected: %v", err)
+	}
+
+	early := validClaims()
+	early["nbf"] = now.Add(10 * time.Second).Unix()
+	if _, err := v.Verify(signToken(t, AlgHS256, "", early, []byte(testHMACSecret))); err != nil {
+		t.Errorf("token valid within the leeway was rejected: %v", err)
+	}
+}
+
+func TestNewJWTVerifierConfig(t *testing.T) {
+	if _, err := NewJWTVerifier(config.JWTConfig{Algorithms: []string{"none"}}); err == nil {
+		t.Error("unsupported algorithm was accepted")
+	}
+	if _, err := NewJWTVerifier(config.JWTConfig{Algorithms: []string{AlgHS256}}); err == nil {
+		t.Error("HS256 without a secret was accepted")
+	}
+}
@@ -0,0 +1,187 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned instead of calling upstream while the breaker is open
+var ErrOpen = errors.New("upstream circuit breaker is open")
+
+// Breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Status reports the breaker state
+type Status struct {
+	State            string    `json:"state"`
+	Forced           bool      `json:"forced"`
+	Failures         int       `json:"consecutive_failures"`
+	FailureThreshold int       `json:"failure_threshold"`
+	Cooldown         string    `json:"cooldown"`
+	OpenedAt         time.Time `json:"opened_at,omitempty"`
+	RetryAt          time.Time `json:"retry_at,omitempty"`
+}
+
+// Breaker stops upstream calls after threshold consecutive failures. Once
+// cooldown has passed it lets a single trial call through: success closes the
+// breaker again and failure reopens it for another cooldown. Operators can pin
+// it open or closed with Force until Reset.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	forced   bool
+	failures int
+	openedAt time.Time
+	trialAt  time.Time
+}
+
+// New creates a closed breaker
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     StateClosed,
+	}
+}
+
+// Allow reports whether an upstream call may be made, returning ErrOpen if
+// not. Every allowed call must be followed by Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case StateOpen:
+		if b.forced || now.Sub(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.state = StateHalfOpen
+		b.trialAt = now
+		return nil
+	case StateHalfOpen:
+		// A trial whose outcome was never reported must not block the breaker forever
+		if now.Sub(b.trialAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.trialAt = now
+		return nil
+	}
+	return nil
+}
+
+// Success records a call that reached a healthy upstream and closes the breaker
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.forced {
+		return
+	}
+	b.state = StateClosed
+	b.failures = 0
+}
+
+// Failure records a failed call, opening the breaker at the threshold or when a trial call fails
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.forced {
+		return
+	}
+	b.failures++
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Force pins the breaker open or closed until Reset
+func (b *Breaker) Force(state string) error {
+	if state != StateOpen && state != StateClosed {
+		return fmt.Errorf("cannot force the breaker %s", state)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = state
+	b.forced = true
+	b.failures = 0
+	if state == StateOpen {
+		b.openedAt = b.now()
+	}
+	return nil
+}
+
+// Reset closes the breaker, clears its failure count and ends any Force
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.forced = false
+	b.failures = 0
+}
+
+// RetryAfter returns how long until an open breaker lets a trial call
+// through, or zero when calls are allowed or the breaker is forced open
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateOpen || b.forced {
+		return 0
+	}
+	if wait := b.openedAt.Add(b.cooldown).Sub(b.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// Ready reports an error while the breaker keeps calls from reaching upstream
+func (b *Breaker) Ready() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateOpen {
+		return nil
+	}
+	if b.forced {
+		return fmt.Errorf("%w: forced open", ErrOpen)
+	}
+	return fmt.Errorf("%w after %d consecutive failures", ErrOpen, b.failures)
+}
+
+// Status returns the current breaker state
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		State:            b.state,
+		Forced:           b.forced,
+		Failures:         b.failures,
+		FailureThreshold: b.threshold,
+		Cooldown:         b.cooldown.String(),
+	}
+	if b.state != StateClosed {
+		status.OpenedAt = b.openedAt
+		if !b.forced {
+			status.RetryAt = b.openedAt.Add(b.cooldown)
+		}
+	}
+	return status
+}
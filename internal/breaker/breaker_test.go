@@ -0,0 +1,126 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker whose clock only moves when advance is called
+func newTestBreaker(threshold int, cooldown time.Duration) (*Breaker, func(time.Duration)) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	b := New(threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestBreakerOpensAtThreshold(t *testing.T) {
+	b, _ := newTestBreaker(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		b.Failure()
+	}
+	// A success resets the consecutive failure count
+	b.Success()
+	for i := 0; i < 2; i++ {
+		b.Failure()
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("Allow after 2 consecutive failures = %v, want nil", err)
+	}
+
+	b.Failure()
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow after 3 consecutive failures = %v, want ErrOpen", err)
+	}
+	if err := b.Ready(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Ready = %v, want ErrOpen", err)
+	}
+	if got := b.RetryAfter(); got != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", got)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name   string
+		report func(*Breaker)
+		state  string
+	}{
+		{"trial success closes", (*Breaker).Success, StateClosed},
+		{"trial failure reopens", (*Breaker).Failure, StateOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, advance := newTestBreaker(1, time.Minute)
+			b.Failure()
+
+			advance(59 * time.Second)
+			if err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Fatalf("Allow before the cooldown = %v, want ErrOpen", err)
+			}
+			advance(time.Second)
+			if err := b.Allow(); err != nil {
+				t.Fatalf("trial call = %v, want nil", err)
+			}
+			if err := b.Allow(); !errors.Is(err, ErrOpen) {
+				t.Fatalf("second call during the trial = %v, want ErrOpen", err)
+			}
+
+			tt.report(b)
+			if got := b.Status().State; got != tt.state {
+				t.Errorf("state = %s, want %s", got, tt.state)
+			}
+		})
+	}
+}
+
+func TestBreakerUnreportedTrial(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+	b.Failure()
+	advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial call = %v", err)
+	}
+
+	// The trial never reported back, so another is allowed after a cooldown
+	advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow after an abandoned trial = %v, want nil", err)
+	}
+}
+
+func TestBreakerForce(t *testing.T) {
+	b, advance := newTestBreaker(1, time.Minute)
+
+	if err := b.Force(StateHalfOpen); err == nil {
+		t.Error("forcing half open should fail")
+	}
+
+	if err := b.Force(StateOpen); err != nil {
+		t.Fatalf("Force open: %v", err)
+	}
+	advance(time.Hour)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow while forced open = %v, want ErrOpen", err)
+	}
+	b.Success()
+	if status := b.Status(); status.State != StateOpen || !status.Forced || !status.RetryAt.IsZero() {
+		t.Errorf("status while forced open = %+v", status)
+	}
+
+	if err := b.Force(StateClosed); err != nil {
+		t.Fatalf("Force closed: %v", err)
+	}
+	b.Failure()
+	b.Failure()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow while forced closed = %v, want nil", err)
+	}
+
+	b.Reset()
+	b.Failure()
+	if status := b.Status(); status.State != StateOpen || status.Forced {
+		t.Errorf("status after Reset and a failure = %+v, want automatically open", status)
+	}
+}
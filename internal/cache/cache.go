@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// Entry is an encoded cached value and its lifetime
+type Entry struct {
+	Key       string
+	Value     []byte
+	StoredAt  time.Time
+	ExpiresAt time.Time
+}
+
+// Expired reports whether the entry is past its TTL at now; it may still be
+// served as stale data until the stale window ends
+func (e Entry) Expired(now time.Time) bool {
+	return now.After(e.ExpiresAt)
+}
+
+// Backend is a TTL cache of encoded values that may be shared by several
+// server instances. Expired entries are kept for a configured stale window so
+// they can still be served when fresh data cannot be fetched.
+type Backend interface {
+	// Get returns the entry stored under key if it is within its stale window
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// DeleteFunc removes every entry whose key matches and returns how many were removed
+	DeleteFunc(ctx context.Context, match func(key string) bool) (int, error)
+	// Clear removes every entry and returns how many were removed
+	Clear(ctx context.Context) (int, error)
+	// Entries returns the entries within their stale window, sorted by key
+	Entries(ctx context.Context) ([]Entry, error)
+	// Lock tries to take the lock named key for at most ttl. ok is false when
+	// another holder has it; otherwise unlock releases it.
+	Lock(ctx context.Context, key string, ttl time.Duration) (unlock func(), ok bool, err error)
+	// Ping checks that the backend is reachable
+	Ping(ctx context.Context) error
+	// Close releases connections held by the backend
+	Close() error
+}
+
+// Open creates the backend selected by the cache configuration
+func Open(cfg config.CacheConfig) (Backend, error) {
+	maxStale := time.Duration(cfg.MaxStale) * time.Second
+	switch cfg.Backend {
+	case "", "memory":
+		return NewMemory(maxStale), nil
+	case "redis":
+		return NewRedis(cfg.Redis, maxStale)
+	}
+	return nil, fmt.Errorf("unknown cache backend %q (available: memory, redis)", cfg.Backend)
+}
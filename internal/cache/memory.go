@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired entries are removed during writes
+const sweepInterval = time.Minute
+
+// Memory is an in-process Backend. Its locks only coalesce refreshes within
+// this instance.
+type Memory struct {
+	maxStale time.Duration
+
+	mu        sync.RWMutex
+	entries   map[string]Entry
+	locks     map[string]lock
+	lockSeq   uint64
+	lastSweep time.Time
+}
+
+type lock struct {
+	id        uint64
+	expiresAt time.Time
+}
+
+// NewMemory creates an empty cache retaining expired entries for maxStale
+func NewMemory(maxStale time.Duration) *Memory {
+	return &Memory{
+		maxStale:  maxStale,
+		entries:   make(map[string]Entry),
+		locks:     make(map[string]lock),
+		lastSweep: time.Now(),
+	}
+}
+
+// Get returns the entry stored under key if it is within its stale window
+func (m *Memory) Get(ctx context.Context, key string) (Entry, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	e, ok := m.entries[key]
+	if !ok || time.Now().After(e.ExpiresAt.Add(m.maxStale)) {
+		return Entry{}, false, nil
+	}
+	return e, true, nil
+}
+
+// Set stores value under key for the given TTL
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.entries[key] = Entry{Key: key, Value: value, StoredAt: now, ExpiresAt: now.Add(ttl)}
+
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+	return nil
+}
+
+// DeleteFunc removes every entry whose key matches and returns how many were removed
+func (m *Memory) DeleteFunc(ctx context.Context, match func(key string) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for key := range m.entries {
+		if match(key) {
+			delete(m.entries, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Clear removes every entry and returns how many were removed
+func (m *Memory) Clear(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.entries)
+	m.entries = make(map[string]Entry)
+	return n, nil
+}
+
+// Entries returns the entries still within their stale window, sorted by key
+func (m *Memory) Entries(ctx context.Context) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(m.entries))
+	for _, e := range m.entries {
+		if now.After(e.ExpiresAt.Add(m.maxStale)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// Lock takes the lock named key unless another caller holds an unexpired one
+func (m *Memory) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if held, ok := m.locks[key]; ok && now.Before(held.expiresAt) {
+		return nil, false, nil
+	}
+	m.lockSeq++
+	id := m.lockSeq
+	m.locks[key] = lock{id: id, expiresAt: now.Add(ttl)}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		// Only release the lock if it has not expired and been taken by someone else
+		if m.locks[key].id == id {
+			delete(m.locks, key)
+		}
+	}, true, nil
+}
+
+// Ping always succeeds for the in-memory cache
+func (m *Memory) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op for the in-memory cache
+func (m *Memory) Close() error {
+	return nil
+}
+
+// sweep removes entries past their stale window and expired locks; callers must hold m.mu for writing
+func (m *Memory) sweep(now time.Time) {
+	for key, e := range m.entries {
+		if now.After(e.ExpiresAt.Add(m.maxStale)) {
+			delete(m.entries, key)
+		}
+	}
+	for key, l := range m.locks {
+		if now.After(l.expiresAt) {
+			delete(m.locks, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisFormatVersion is written with every entry. Entries in any other
+// format, e.g. from instances running an older release during a rolling
+// deploy, are treated as misses and overwritten.
+const redisFormatVersion = 1
+
+// scanBatch is the number of keys requested per SCAN round trip
+const scanBatch = 200
+
+// redisEntry is the stored form of an Entry
+type redisEntry struct {
+	Version   int             `json:"v"`
+	StoredAt  time.Time       `json:"stored_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// unlockScript deletes a lock only if it still holds the caller's token
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Redis is a Backend shared by every instance connected to the same Redis
+// server. Entries live under "<prefix>entry:<key>" and locks under
+// "<prefix>lock:<key>"; Redis expires entries once their stale window ends.
+type Redis struct {
+	client   *redis.Client
+	prefix   string
+	maxStale time.Duration
+}
+
+// NewRedis connects to the configured Redis server
+func NewRedis(cfg config.RedisConfig, maxStale time.Duration) (*Redis, error) {
+	opts := &redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	}
+	if cfg.TLS {
+		opts.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return &Redis{
+		client:   redis.NewClient(opts),
+		prefix:   cfg.KeyPrefix,
+		maxStale: maxStale,
+	}, nil
+}
+
+// Get returns the entry stored under key if it is within its stale window
+func (r *Redis) Get(ctx context.Context, key string) (Entry, bool, error) {
+	raw, err := r.client.Get(ctx, r.entryKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	e, ok := r.decode(key, raw)
+	return e, ok, nil
+}
+
+// Set stores value under key, letting Redis expire it when its stale window ends
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	raw, err := json.Marshal(redisEntry{
+		Version:   redisFormatVersion,
+		StoredAt:  now,
+		ExpiresAt: now.Add(ttl),
+		Value:     value,
+	})
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.entryKey(key), raw, ttl+r.maxStale).Err()
+}
+
+// DeleteFunc removes every entry whose key matches and returns how many were removed
+func (r *Redis) DeleteFunc(ctx context.Context, match func(key string) bool) (int, error) {
+	var matched []string
+	err := r.scan(ctx, func(redisKey string) {
+		if match(strings.TrimPrefix(redisKey, r.entryKey(""))) {
+			matched = append(matched, redisKey)
+		}
+	})
+	if err != nil || len(matched) == 0 {
+		return 0, err
+	}
+	n, err := r.client.Del(ctx, matched...).Result()
+	return int(n), err
+}
+
+// Clear removes every entry under the key prefix and returns how many were removed
+func (r *Redis) Clear(ctx context.Context) (int, error) {
+	return r.DeleteFunc(ctx, func(string) bool { return true })
+}
+
+// Entries returns the entries under the key prefix, sorted by key
+func (r *Redis) Entries(ctx context.Context) ([]Entry, error) {
+	var keys []string
+	if err := r.scan(ctx, func(redisKey string) { keys = append(keys, redisKey) }); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(keys))
+	for start := 0; start < len(keys); start += scanBatch {
+		batch := keys[start:min(start+scanBatch, len(keys))]
+		values, err := r.client.MGet(ctx, batch...).Result()
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			raw, ok := v.(string)
+			if !ok {
+				// Expired between SCAN and MGET
+				continue
+			}
+			if e, ok := r.decode(strings.TrimPrefix(batch[i], r.entryKey("")), []byte(raw)); ok {
+				entries = append(entries, e)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// Lock takes the lock named key with SET NX, so only one instance holds it
+// at a time. The lock expires after ttl if its holder never releases it.
+func (r *Redis) Lock(ctx context.Context, key string, ttl time.Duration) (func(), bool, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, false, err
+	}
+	lockKey := r.prefix + "lock:" + key
+	ok, err := r.client.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	return func() {
+		// Release with a fresh context so a cancelled request still unlocks
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		unlockScript.Run(ctx, r.client, []string{lockKey}, token)
+	}, true, nil
+}
+
+// Ping checks that the Redis server answers
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the connection pool
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) entryKey(key string) string {
+	return r.prefix + "entry:" + key
+}
+
+// scan calls fn with every entry key under the prefix
+func (r *Redis) scan(ctx context.Context, fn func(redisKey string)) error {
+	iter := r.client.Scan(ctx, 0, escapeGlob(r.entryKey(""))+"*", scanBatch).Iterator()
+	for iter.Next(ctx) {
+		fn(iter.Val())
+	}
+	return iter.Err()
+}
+
+// decode unpacks a stored entry, reporting false for other format versions
+// and entries already past their stale window
+func (r *Redis) decode(key string, raw []byte) (Entry, bool) {
+	var stored redisEntry
+	if err := json.Unmarshal(raw, &stored); err != nil || stored.Version != redisFormatVersion {
+		return Entry{}, false
+	}
+	if time.Now().After(stored.ExpiresAt.Add(r.maxStale)) {
+		return Entry{}, false
+	}
+	return Entry{Key: key, Value: stored.Value, StoredAt: stored.StoredAt, ExpiresAt: stored.ExpiresAt}, true
+}
+
+// escapeGlob escapes the characters SCAN MATCH treats specially
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func randomToken() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,475 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// fakeRedis is an in-process stand-in for the subset of Redis the backend
+// uses: GET, SET with PX/EX and NX, DEL, MGET, SCAN with MATCH, PTTL, PING
+// and the unlock script. It speaks RESP2 and rejects HELLO, so clients fall
+// back to RESP2 as they do with older servers.
+type fakeRedis struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	conns   map[net.Conn]bool
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{
+		listener: listener,
+		values:   make(map[string]string),
+		expires:  make(map[string]time.Time),
+		conns:    make(map[net.Conn]bool),
+	}
+	go f.serve()
+	t.Cleanup(f.close)
+	return f
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+// connections returns the number of open client connections
+func (f *fakeRedis) connections() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.conns)
+}
+
+func (f *fakeRedis) close() {
+	f.listener.Close()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for conn := range f.conns {
+		conn.Close()
+	}
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns[conn] = true
+		f.mu.Unlock()
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer func() {
+		f.mu.Lock()
+		delete(f.conns, conn)
+		f.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		f.exec(w, args)
+		// Pipelined commands are answered together
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readCommand reads a RESP array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// get returns a live value; callers must hold f.mu
+func (f *fakeRedis) get(key string) (string, bool) {
+	if at, ok := f.expires[key]; ok && !time.Now().Before(at) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	v, ok := f.values[key]
+	return v, ok
+}
+
+func (f *fakeRedis) exec(w *bufio.Writer, args []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		fmt.Fprint(w, "+PONG\r\n")
+	case "GET":
+		v, ok := f.get(args[1])
+		writeBulk(w, v, ok)
+	case "SET":
+		key, value := args[1], args[2]
+		var ttl time.Duration
+		nx := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX", "EX":
+				n, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(n) * time.Millisecond
+				if strings.ToUpper(args[i]) == "EX" {
+					ttl = time.Duration(n) * time.Second
+				}
+				i++
+			}
+		}
+		if _, exists := f.get(key); nx && exists {
+			writeBulk(w, "", false)
+			return
+		}
+		f.values[key] = value
+		delete(f.expires, key)
+		if ttl > 0 {
+			f.expires[key] = time.Now().Add(ttl)
+		}
+		fmt.Fprint(w, "+OK\r\n")
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := f.get(key); ok {
+				delete(f.values, key)
+				delete(f.expires, key)
+				n++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", n)
+	case "MGET":
+		fmt.Fprintf(w, "*%d\r\n", len(args)-1)
+		for _, key := range args[1:] {
+			v, ok := f.get(key)
+			writeBulk(w, v, ok)
+		}
+	case "PTTL":
+		if _, ok := f.get(args[1]); !ok {
+			fmt.Fprint(w, ":-2\r\n")
+		} else if at, ok := f.expires[args[1]]; ok {
+			fmt.Fprintf(w, ":%d\r\n", time.Until(at).Milliseconds())
+		} else {
+			fmt.Fprint(w, ":-1\r\n")
+		}
+	case "SCAN":
+		// Every key is returned in one round, with cursor 0 ending the scan
+		pattern := "*"
+		for i := 2; i < len(args)-1; i++ {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		var keys []string
+		for key := range f.values {
+			if _, ok := f.get(key); !ok {
+				continue
+			}
+			if ok, _ := path.Match(pattern, key); ok {
+				keys = append(keys, key)
+			}
+		}
+		fmt.Fprintf(w, "*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+		for _, key := range keys {
+			writeBulk(w, key, true)
+		}
+	case "EVALSHA":
+		fmt.Fprint(w, "-NOSCRIPT No matching script\r\n")
+	case "EVAL":
+		// The only script the backend runs is the compare-and-delete unlock
+		key, token := args[3], args[4]
+		if v, ok := f.get(key); ok && v == token {
+			delete(f.values, key)
+			delete(f.expires, key)
+			fmt.Fprint(w, ":1\r\n")
+			return
+		}
+		fmt.Fprint(w, ":0\r\n")
+	default:
+		fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func writeBulk(w *bufio.Writer, v string, ok bool) {
+	if !ok {
+		fmt.Fprint(w, "$-1\r\n")
+		return
+	}
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+}
+
+func newTestRedis(t *testing.T, f *fakeRedis, prefix string, maxStale time.Duration) *Redis {
+	t.Helper()
+	r, err := NewRedis(config.RedisConfig{Address: f.addr(), KeyPrefix: prefix}, maxStale)
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestRedisGetSet(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRedis(t, newFakeRedis(t), "test:", time.Minute)
+
+	if err := r.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if _, ok, err := r.Get(ctx, "weather|city:london"); err != nil || ok {
+		t.Fatalf("Get before Set = ok %v, err %v; want a miss", ok, err)
+	}
+
+	before := time.Now()
+	if err := r.Set(ctx, "weather|city:london", []byte(`{"temp":15}`), 5*time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	e, ok, err := r.Get(ctx, "weather|city:london")
+	if err != nil || !ok {
+		t.Fatalf("Get after Set = ok %v, err %v", ok, err)
+	}
+	if e.Key != "weather|city:london" || string(e.Value) != `{"temp":15}` {
+		t.Errorf("entry = %q %s", e.Key, e.Value)
+	}
+	if e.StoredAt.Before(before) || e.ExpiresAt.Sub(e.StoredAt) != 5*time.Minute {
+		t.Errorf("stored %v, expires %v; want a 5m TTL from now", e.StoredAt, e.ExpiresAt)
+	}
+	if e.Expired(time.Now()) {
+		t.Error("fresh entry reports expired")
+	}
+
+	// Redis holds the key for the TTL plus the stale window
+	pttl, err := r.client.PTTL(ctx, "test:entry:weather|city:london").Result()
+	if err != nil {
+		t.Fatalf("PTTL: %v", err)
+	}
+	if pttl <= 5*time.Minute || pttl > 6*time.Minute {
+		t.Errorf("redis TTL = %v, want just under 6m", pttl)
+	}
+}
+
+func TestRedisTTL(t *testing.T) {
+	ctx := context.Background()
+	const ttl, maxStale = 100 * time.Millisecond, 200 * time.Millisecond
+	r := newTestRedis(t, newFakeRedis(t), "test:", maxStale)
+
+	if err := r.Set(ctx, "forecast|city:paris", []byte(`[]`), ttl); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	time.Sleep(ttl + 50*time.Millisecond)
+	e, ok, err := r.Get(ctx, "forecast|city:paris")
+	if err != nil || !ok {
+		t.Fatalf("Get within the stale window = ok %v, err %v; want the stale entry", ok, err)
+	}
+	if !e.Expired(time.Now()) {
+		t.Error("entry past its TTL does not report expired")
+	}
+
+	time.Sleep(maxStale)
+	if _, ok, err := r.Get(ctx, "forecast|city:paris"); err != nil || ok {
+		t.Errorf("Get after the stale window = ok %v, err %v; want a miss", ok, err)
+	}
+}
+
+func TestRedisStaleEntryIgnoredBeforeRedisExpiry(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t)
+	// Written by an instance with a longer stale window than this one
+	long := newTestRedis(t, f, "test:", time.Hour)
+	short := newTestRedis(t, f, "test:", 0)
+
+	if err := long.Set(ctx, "weather|city:rome", []byte(`{}`), 50*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, _ := short.Get(ctx, "weather|city:rome"); ok {
+		t.Error("entry past this instance's stale window was returned")
+	}
+	if entries, _ := short.Entries(ctx); len(entries) != 0 {
+		t.Errorf("Entries = %v, want none", entries)
+	}
+}
+
+func TestRedisMisses(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRedis(t, newFakeRedis(t), "test:", time.Minute)
+
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"other format version", `{"v":2,"stored_at":"2026-01-01T00:00:00Z","expires_at":"2999-01-01T00:00:00Z","value":{}}`},
+		{"not json", `weather`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.client.Set(ctx, r.entryKey("weather|city:oslo"), tt.raw, 0).Err(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, err := r.Get(ctx, "weather|city:oslo"); err != nil || ok {
+				t.Errorf("Get = ok %v, err %v; want a miss", ok, err)
+			}
+		})
+	}
+}
+
+func TestRedisEntriesAndDelete(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t)
+	// Glob characters in the prefix must not match other prefixes
+	r := newTestRedis(t, f, "app[1]:", time.Minute)
+	other := newTestRedis(t, f, "app1:", time.Minute)
+
+	for _, key := range []string{"weather|city:london", "forecast|city:london", "weather|city:paris"} {
+		if err := r.Set(ctx, key, []byte(`{}`), time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	if err := other.Set(ctx, "weather|city:london", []byte(`{}`), time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	entries, err := r.Entries(ctx)
+	if err != nil {
+		t.Fatalf("Entries: %v", err)
+	}
+	var keys []string
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	if got := strings.Join(keys, ","); got != "forecast|city:london,weather|city:london,weather|city:paris" {
+		t.Errorf("Entries = %s", got)
+	}
+
+	n, err := r.DeleteFunc(ctx, func(key string) bool { return strings.HasSuffix(key, "city:london") })
+	if err != nil || n != 2 {
+		t.Errorf("DeleteFunc = %d, %v; want 2 removed", n, err)
+	}
+	if n, err := r.Clear(ctx); err != nil || n != 1 {
+		t.Errorf("Clear = %d, %v; want 1 removed", n, err)
+	}
+	if _, ok, _ := other.Get(ctx, "weather|city:london"); !ok {
+		t.Error("Clear removed an entry under another prefix")
+	}
+}
+
+func TestRedisLock(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t)
+	a := newTestRedis(t, f, "test:", time.Minute)
+	b := newTestRedis(t, f, "test:", time.Minute)
+
+	unlock, ok, err := a.Lock(ctx, "weather|city:london", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("first Lock = ok %v, err %v", ok, err)
+	}
+	if _, ok, err := b.Lock(ctx, "weather|city:london", time.Minute); err != nil || ok {
+		t.Fatalf("second Lock = ok %v, err %v; want it held", ok, err)
+	}
+	unlock()
+	unlockB, ok, err := b.Lock(ctx, "weather|city:london", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("Lock after unlock = ok %v, err %v", ok, err)
+	}
+
+	// A stale unlock must not release a lock someone else now holds
+	unlock()
+	if _, ok, _ := a.Lock(ctx, "weather|city:london", time.Minute); ok {
+		t.Error("stale unlock released another holder's lock")
+	}
+	unlockB()
+
+	// Locks expire if their holder never releases them
+	if _, ok, _ := a.Lock(ctx, "weather|city:paris", 50*time.Millisecond); !ok {
+		t.Fatal("Lock failed")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, _ := b.Lock(ctx, "weather|city:paris", time.Minute); !ok {
+		t.Error("expired lock was not released")
+	}
+}
+
+func TestRedisClose(t *testing.T) {
+	ctx := context.Background()
+	f := newFakeRedis(t)
+	r, err := NewRedis(config.RedisConfig{Address: f.addr(), KeyPrefix: "test:"}, time.Minute)
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	if err := r.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for f.connections() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := f.connections(); n != 0 {
+		t.Errorf("%d connections still open after Close", n)
+	}
+	if err := r.Ping(ctx); err == nil {
+		t.Error("Ping succeeded after Close")
+	}
+	if _, _, err := r.Get(ctx, "weather|city:london"); err == nil {
+		t.Error("Get succeeded after Close")
+	}
+}
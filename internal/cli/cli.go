@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/logging"
+	"github.com/ANAS727189/weather-project/pkg/server"
+)
+
+// Run parses command-line arguments, loads configuration and runs the server
+// until it is shut down. "validate-config" as the first argument checks the
+// configuration instead.
+func Run(args []string) error {
+	if len(args) > 0 && args[0] == "validate-config" {
+		return validateConfig(args[1:], os.Stdout)
+	}
+
+	flags := flag.NewFlagSet("weather-api", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to a JSON, YAML or TOML config file")
+	port := flags.String("port", "", "port to listen on (overrides PORT and the config file)")
+	logLevel := flags.String("log-level", "", "debug, info, warn or error (overrides LOG_LEVEL and the config file)")
+	flags.Usage = func() { usage(flags.Output(), flags) }
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	// Flags take precedence over the config file and environment variables
+	var overrides []func(*config.Config)
+	if *port != "" {
+		overrides = append(overrides, func(cfg *config.Config) { cfg.Server.Port = *port })
+	}
+	if *logLevel != "" {
+		overrides = append(overrides, func(cfg *config.Config) { cfg.Log.Level = *logLevel })
+	}
+
+	cfg, err := config.LoadConfig(*configPath, overrides...)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %v", err)
+	}
+	if err := logging.Setup(cfg.Log, os.Stderr); err != nil {
+		return fmt.Errorf("failed to configure logging: %v", err)
+	}
+	for _, warning := range cfg.Warnings() {
+		slog.Warn("insecure configuration", "warning", warning)
+	}
+
+	srv := server.NewServer(cfg)
+	srv.EnableReload(func() (*config.Config, error) {
+		return config.LoadConfig(*configPath, overrides...)
+	}, *configPath)
+	return srv.Start()
+}
+
+func usage(w io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintf(w, "Usage: weather-api [flags]\n")
+	fmt.Fprintf(w, "       weather-api validate-config [--config path] [--strict]\n\n")
+	fmt.Fprintf(w, "Settings are applied in order: defaults, config file, environment variables, flags.\n\n")
+	fmt.Fprintf(w, "Flags:\n")
+	flags.PrintDefaults()
+}
@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+
+	// Register the config checks of packages the server uses
+	_ "github.com/ANAS727189/weather-project/internal/collector"
+	_ "github.com/ANAS727189/weather-project/internal/storage"
+)
+
+// validateConfig implements the validate-config subcommand: it loads the
+// configuration exactly as the server would, without starting it, and reports
+// every problem and warning. It fails when the configuration is invalid, and
+// with --strict also when there are warnings.
+func validateConfig(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("weather-api validate-config", flag.ContinueOnError)
+	configPath := flags.String("config", "", "path to a JSON, YAML or TOML config file")
+	strict := flags.Bool("strict", false, "treat warnings as errors")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: weather-api validate-config [flags]\n\n")
+		fmt.Fprintf(flags.Output(), "Checks the config file and environment variables without starting the server.\n\n")
+		fmt.Fprintf(flags.Output(), "Flags:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return fmt.Errorf("configuration is invalid")
+	}
+
+	warnings := cfg.Warnings()
+	for _, warning := range warnings {
+		fmt.Fprintf(out, "warning: %s\n", warning)
+	}
+	if *strict && len(warnings) > 0 {
+		return fmt.Errorf("configuration has %d warnings", len(warnings))
+	}
+
+	fmt.Fprintln(out, "configuration is valid")
+	return nil
+}
@@ -0,0 +1,267 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
+	"github.com/ANAS727189/weather-project/internal/services"
+)
+
+// Status reports the state of the collector and its most recent run
+type Status struct {
+	Running         bool      `json:"running"`
+	Schedule        string    `json:"schedule"`
+	Locations       int       `json:"locations"`
+	LastRunStarted  time.Time `json:"last_run_started,omitempty"`
+	LastRunFinished time.Time `json:"last_run_finished,omitempty"`
+	LastRunDuration string    `json:"last_run_duration,omitempty"`
+	Succeeded       int       `json:"succeeded"`
+	Failed          int       `json:"failed"`
+	LastError       string    `json:"last_error,omitempty"`
+	NextRun         time.Time `json:"next_run,omitempty"`
+}
+
+// clock tells the time and waits; tests substitute a fake to drive the
+// schedule and request spacing without sleeping
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the wall clock
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Collector polls a fixed list of locations on a schedule, warming the
+// weather cache and recording observations to the history store
+type Collector struct {
+	weatherService *services.WeatherService
+	schedule       Schedule
+	spec           string
+	locations      []models.Location
+	spacing        time.Duration
+	clock          clock
+
+	mu     sync.Mutex
+	status Status
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a collector from configuration
+func New(cfg config.CollectorConfig, weatherService *services.WeatherService) (*Collector, error) {
+	schedule, err := ParseSchedule(cfg.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collector schedule %q: %v", cfg.Schedule, err)
+	}
+
+	entries := append([]string{}, cfg.Cities...)
+	if cfg.CitiesFile != "" {
+		fileEntries, err := readCitiesFile(cfg.CitiesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read collector cities file: %v", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	locations, err := parseLocations(entries)
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("collector has no locations to poll")
+	}
+
+	return &Collector{
+		weatherService: weatherService,
+		schedule:       schedule,
+		spec:           cfg.Schedule,
+		locations:      locations,
+		spacing:        time.Minute / time.Duration(cfg.RequestsPerMinute),
+		clock:          realClock{},
+		status: Status{
+			Schedule:  cfg.Schedule,
+			Locations: len(locations),
+		},
+	}, nil
+}
+
+// Start begins running collections on the schedule in the background
+func (c *Collector) Start() {
+	ctx, cancel := context.WithCancel(quota.WithPriority(context.Background(), quota.PriorityBackground))
+	c.cancel = cancel
+
+	c.mu.Lock()
+	c.status.Running = true
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go c.loop(ctx)
+
+	slog.Info("collector: started", "locations", len(c.locations), "schedule", c.spec)
+}
+
+// Stop cancels any in-progress run and waits for the collector to exit
+func (c *Collector) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.wg.Wait()
+
+	c.mu.Lock()
+	c.status.Running = false
+	c.status.NextRun = time.Time{}
+	c.mu.Unlock()
+}
+
+// Status returns a snapshot of the collector status
+func (c *Collector) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.status
+}
+
+// loop waits for each scheduled time and runs a collection
+func (c *Collector) loop(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		now := c.clock.Now()
+		next := c.schedule.Next(now)
+		if next.IsZero() {
+			slog.Warn("collector: schedule never fires, stopping", "schedule", c.spec)
+			return
+		}
+
+		c.mu.Lock()
+		c.status.NextRun = next
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.clock.After(next.Sub(now)):
+		}
+
+		// Runs never overlap: a run that outlasts the interval delays the next one
+		c.run(ctx)
+	}
+}
+
+// run polls every location once, spacing upstream requests to stay within budget
+func (c *Collector) run(ctx context.Context) {
+	started := c.clock.Now()
+	c.mu.Lock()
+	c.status.LastRunStarted = started
+	c.mu.Unlock()
+
+	succeeded, failed := 0, 0
+	var lastErr error
+
+	for i, loc := range c.locations {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				c.finishRun(started, succeeded, failed, ctx.Err())
+				return
+			case <-c.clock.After(c.spacing):
+			}
+		}
+
+		if _, err := c.weatherService.RefreshCurrentWeather(ctx, loc); err != nil {
+			if ctx.Err() != nil {
+				c.finishRun(started, succeeded, failed, ctx.Err())
+				return
+			}
+			failed++
+			lastErr = fmt.Errorf("%s: %v", loc, err)
+			continue
+		}
+		succeeded++
+	}
+
+	c.finishRun(started, succeeded, failed, lastErr)
+	slog.Info("collector: run finished", "duration", c.clock.Now().Sub(started).Round(time.Millisecond), "succeeded", succeeded, "failed", failed)
+}
+
+func (c *Collector) finishRun(started time.Time, succeeded, failed int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	finished := c.clock.Now()
+	c.status.LastRunFinished = finished
+	c.status.LastRunDuration = finished.Sub(started).Round(time.Millisecond).String()
+	c.status.Succeeded = succeeded
+	c.status.Failed = failed
+	c.status.LastError = ""
+	if err != nil {
+		c.status.LastError = err.Error()
+	}
+}
+
+// readCitiesFile reads one location per line, ignoring blank lines and # comments
+func readCitiesFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+// parseLocations turns city names and "lat,lon" pairs into unique locations
+func parseLocations(entries []string) ([]models.Location, error) {
+	var locations []models.Location
+	seen := make(map[string]bool)
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		loc := models.CityLocation(entry)
+		if parts := strings.Split(entry, ","); len(parts) == 2 {
+			lat, latErr := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			lon, lonErr := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if latErr == nil && lonErr == nil {
+				if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+					return nil, fmt.Errorf("collector coordinates out of range: %q", entry)
+				}
+				loc = models.CoordLocation(lat, lon)
+			}
+		}
+
+		if seen[loc.Key()] {
+			continue
+		}
+		seen[loc.Key()] = true
+		locations = append(locations, loc)
+	}
+	return locations, nil
+}
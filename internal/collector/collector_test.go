@@ -0,0 +1,158 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/services"
+)
+
+// fakeClock advances instantly through every wait and records its length.
+// Once limit waits have been requested it cancels the collector and blocks.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	waits  []time.Duration
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waits = append(c.waits, d)
+	if len(c.waits) >= c.limit {
+		c.cancel()
+		return nil
+	}
+	c.now = c.now.Add(d)
+	fired := make(chan time.Time, 1)
+	fired <- c.now
+	return fired
+}
+
+// newTestCollector returns a collector for cities whose upstream answers every
+// request, counting them in requests
+func newTestCollector(t *testing.T, cfg config.CollectorConfig, requests *atomic.Int64) *Collector {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("q") == "atlantis" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": r.URL.Query().Get("q")})
+	}))
+	t.Cleanup(upstream.Close)
+
+	ws := services.NewWeatherService(&config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}, nil, nil, nil, nil)
+	c, err := New(cfg, ws)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c
+}
+
+func TestCollectorRunsOnScheduleWithSpacing(t *testing.T) {
+	var requests atomic.Int64
+	c := newTestCollector(t, config.CollectorConfig{
+		Schedule:          "*/10 * * * *",
+		Cities:            []string{"London", "Paris", "Atlantis"},
+		RequestsPerMinute: 30,
+	}, &requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{now: time.Date(2025, 1, 15, 10, 3, 0, 0, time.UTC), limit: 6, cancel: cancel}
+	c.clock = clock
+
+	c.wg.Add(1)
+	c.loop(ctx)
+
+	// Wait for 10:10, space the three requests 2s apart, then wait for 10:20
+	// and cancel while spacing the second run
+	want := []time.Duration{7 * time.Minute, 2 * time.Second, 2 * time.Second, 10*time.Minute - 4*time.Second, 2 * time.Second, 2 * time.Second}
+	if len(clock.waits) < len(want) || !reflect.DeepEqual(clock.waits[:len(want)], want) {
+		t.Errorf("waits = %v, want %v", clock.waits, want)
+	}
+	if got := requests.Load(); got != 5 {
+		t.Errorf("upstream requests = %d, want 5", got)
+	}
+
+	status := c.Status()
+	if !status.LastRunStarted.Equal(time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC)) {
+		t.Errorf("last run started at %v, want 10:20", status.LastRunStarted)
+	}
+	if !status.NextRun.Equal(time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("next run = %v, want 10:30", status.NextRun)
+	}
+	if status.Succeeded != 2 || status.Failed != 0 || status.LastError != context.Canceled.Error() {
+		t.Errorf("status = %+v, want 2 succeeded and a cancelled run", status)
+	}
+}
+
+func TestCollectorRunRecordsFailures(t *testing.T) {
+	var requests atomic.Int64
+	c := newTestCollector(t, config.CollectorConfig{
+		Schedule:          "@hourly",
+		Cities:            []string{"London", "Atlantis", "51.5,-0.12"},
+		RequestsPerMinute: 60,
+	}, &requests)
+	clock := &fakeClock{now: time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), limit: 100, cancel: func() {}}
+	c.clock = clock
+
+	c.run(context.Background())
+
+	if want := []time.Duration{time.Second, time.Second}; !reflect.DeepEqual(clock.waits, want) {
+		t.Errorf("waits = %v, want %v", clock.waits, want)
+	}
+	status := c.Status()
+	if status.Succeeded != 2 || status.Failed != 1 || status.LastError == "" {
+		t.Errorf("status = %+v, want 2 succeeded and 1 failed", status)
+	}
+	if status.LastRunDuration != "2s" {
+		t.Errorf("duration = %s, want 2s", status.LastRunDuration)
+	}
+}
+
+func TestNewCollectorLocations(t *testing.T) {
+	var requests atomic.Int64
+	c := newTestCollector(t, config.CollectorConfig{
+		Schedule:          "@daily",
+		Cities:            []string{"London", " london ", "", "51.5, -0.12", "51.5,-0.12"},
+		RequestsPerMinute: 60,
+	}, &requests)
+
+	if len(c.locations) != 2 {
+		t.Fatalf("locations = %v, want London and one coordinate pair", c.locations)
+	}
+	if c.spacing != time.Second {
+		t.Errorf("spacing = %v, want 1s", c.spacing)
+	}
+
+	for _, cfg := range []config.CollectorConfig{
+		{Schedule: "every day", Cities: []string{"London"}, RequestsPerMinute: 60},
+		{Schedule: "@daily", RequestsPerMinute: 60},
+		{Schedule: "@daily", Cities: []string{"91,0"}, RequestsPerMinute: 60},
+	} {
+		if _, err := New(cfg, nil); err == nil {
+			t.Errorf("New(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+func init() {
+	config.RegisterCheck("collector.schedule", func(cfg *config.Config) error {
+		if !cfg.Collector.Enabled || cfg.Collector.Schedule == "" {
+			return nil
+		}
+		_, err := ParseSchedule(cfg.Collector.Schedule)
+		return err
+	})
+}
+
+// Schedule determines when collection runs happen
+type Schedule interface {
+	// Next returns the first run time strictly after t
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a standard five-field cron expression
+// (minute hour day-of-month month day-of-week) or one of the descriptors
+// @hourly, @daily and @every <duration>
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch {
+	case spec == "@hourly":
+		spec = "0 * * * *"
+	case spec == "@daily" || spec == "@midnight":
+		spec = "0 0 * * *"
+	case strings.HasPrefix(spec, "@every "):
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid @every interval: %v", err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("@every interval must be at least one second")
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 cron fields, got %d", len(fields))
+	}
+
+	var cs cronSchedule
+	var err error
+	if cs.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %v", err)
+	}
+	if cs.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %v", err)
+	}
+	if cs.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day-of-month field: %v", err)
+	}
+	if cs.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %v", err)
+	}
+	if cs.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day-of-week field: %v", err)
+	}
+	// Both 0 and 7 mean Sunday
+	if cs.dow[7] {
+		cs.dow[0] = true
+	}
+	cs.domAny = fields[2] == "*"
+	cs.dowAny = fields[4] == "*"
+
+	return cs, nil
+}
+
+// everySchedule runs at a fixed interval
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(time.Second).Add(s.interval)
+}
+
+// cronSchedule matches times against sets of allowed field values
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+// maxSearchYears bounds the search for the next run; it covers a full leap
+// year cycle so schedules for February 29th are found
+const maxSearchYears = 5
+
+func (s cronSchedule) Next(t time.Time) time.Time {
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := next.AddDate(maxSearchYears, 0, 0)
+	loc := next.Location()
+
+	// Skip whole months, days and hours that cannot match
+	for next.Before(limit) {
+		switch {
+		case !s.month[int(next.Month())]:
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, loc)
+		case !s.hour[next.Hour()]:
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, loc)
+		case !s.minute[next.Minute()]:
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	// Unsatisfiable expressions such as February 31st never run
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t satisfies the day-of-month and day-of-week fields
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	// As in cron, a restricted day-of-month and day-of-week match if either does
+	domMatch := s.dom[t.Day()]
+	dowMatch := s.dow[int(t.Weekday())]
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// parseField expands a cron field made of comma separated values, ranges and steps
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:idx]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			val, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			lo = val
+			if step == 1 {
+				hi = val
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+
+	return values, nil
+}
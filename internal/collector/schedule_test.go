@@ -0,0 +1,81 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr string
+	}{
+		{"", "expected 5 cron fields, got 0"},
+		{"* * * *", "expected 5 cron fields, got 4"},
+		{"* * * * * *", "expected 5 cron fields, got 6"},
+		{"60 * * * *", "invalid minute field"},
+		{"* 24 * * *", "invalid hour field"},
+		{"* * 0 * *", "invalid day-of-month field"},
+		{"* * * 13 *", "invalid month field"},
+		{"* * * * 8", "invalid day-of-week field"},
+		{"5-1 * * * *", "outside 0-59"},
+		{"*/0 * * * *", "invalid step"},
+		{"a * * * *", "invalid value"},
+		{"1-x * * * *", "invalid range"},
+		{"@every soon", "invalid @every interval"},
+		{"@every 500ms", "at least one second"},
+		{"@weekly", "expected 5 cron fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseSchedule(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday 15 January 2025, 10:07:30 UTC
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2025, 1, 15, 10, 8, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"range with step", "10-50/20 * * * *", time.Date(2025, 1, 15, 10, 10, 0, 0, time.UTC)},
+		{"list", "5,40 * * * *", time.Date(2025, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{"value with step", "50/5 * * * *", time.Date(2025, 1, 15, 10, 50, 0, 0, time.UTC)},
+		{"hourly", "@hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"daily", "@daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"midnight", "@midnight", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"every", "@every 90s", time.Date(2025, 1, 15, 10, 9, 0, 0, time.UTC)},
+		{"later today", "30 18 * * *", time.Date(2025, 1, 15, 18, 30, 0, 0, time.UTC)},
+		{"earlier hour rolls to tomorrow", "0 9 * * *", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"day of month", "0 0 1 * *", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"month", "0 0 1 6 *", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"weekday", "0 9 * * 1-5", time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"sunday as 0", "0 0 * * 0", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		// With both day fields restricted either may match: the 20th, or the next Friday (17th)
+		{"day of month or day of week", "0 0 20 * 5", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"never", "0 0 31 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule(%q): %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
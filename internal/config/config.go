@@ -5,23 +5,62 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server ServerConfig `json:"server"`
-	API    APIConfig    `json:"api"`
+	Server    ServerConfig    `json:"server"`
+	API       APIConfig       `json:"api"`
+	Auth      AuthConfig      `json:"auth"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	Quota     QuotaConfig     `json:"quota"`
+	Breaker   BreakerConfig   `json:"breaker"`
+	Cache     CacheConfig     `json:"cache"`
+	Stream    StreamConfig    `json:"stream"`
+	Storage   StorageConfig   `json:"storage"`
+	History   HistoryConfig   `json:"history"`
+	Collector CollectorConfig `json:"collector"`
+	Metrics   MetricsConfig   `json:"metrics"`
+	Log       LogConfig       `json:"log"`
+	Tracing   TracingConfig   `json:"tracing"`
+	Health    HealthConfig    `json:"health"`
+	Admin     AdminConfig     `json:"admin"`
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port         string     `json:"port"`
-	Host         string     `json:"host"`
-	ReadTimeout  int        `json:"read_timeout"`
-	WriteTimeout int        `json:"write_timeout"`
-	CORS         CORSConfig `json:"cors"`
+	Port         string            `json:"port"`
+	Host         string            `json:"host"`
+	ReadTimeout  int               `json:"read_timeout"`
+	WriteTimeout int               `json:"write_timeout"`
+	CORS         CORSConfig        `json:"cors"`
+	TLS          TLSConfig         `json:"tls"`
+	Compression  CompressionConfig `json:"compression"`
+}
+
+// CompressionConfig holds response compression configuration
+type CompressionConfig struct {
+	Enabled bool `json:"enabled"`
+	// MinSize is the smallest response body, in bytes, that is compressed
+	MinSize int `json:"min_size"`
+}
+
+// TLSConfig holds HTTPS serving configuration
+type TLSConfig struct {
+	Enabled      bool   `json:"enabled"`
+	CertFile     string `json:"cert_file"`
+	KeyFile      string `json:"key_file"`
+	MinVersion   string `json:"min_version"`
+	CipherPolicy string `json:"cipher_policy"`
+	ClientCAFile string `json:"client_ca_file"`
+	ClientAuth   string `json:"client_auth"`
+	HTTP2        bool   `json:"http2"`
+	RedirectPort string `json:"redirect_port"`
 }
 
 // CORSConfig holds CORS configuration
@@ -37,15 +76,179 @@ type APIConfig struct {
 	OpenWeatherMapApiKey string `json:"openWeatherMapApiKey"`
 	BaseURL              string `json:"base_url"`
 	Timeout              int    `json:"timeout"`
+	MaxRetries           int    `json:"max_retries"`
+
+	// AlertsURL is the One Call endpoint weather alerts are read from; alerts
+	// are unavailable when it is empty
+	AlertsURL string `json:"alerts_url"`
+}
+
+// AuthConfig holds API consumer authentication configuration
+type AuthConfig struct {
+	Enabled     bool              `json:"enabled"`
+	HeaderName  string            `json:"header_name"`
+	QueryParam  string            `json:"query_param"`
+	APIKeys     []APIKeyConfig    `json:"api_keys"`
+	KeysFile    string            `json:"keys_file"`
+	JWT         JWTConfig         `json:"jwt"`
+	RouteScopes map[string]string `json:"route_scopes"`
+}
+
+// JWTConfig holds bearer token verification configuration
+type JWTConfig struct {
+	Enabled       bool                `json:"enabled"`
+	Algorithms    []string            `json:"algorithms"`
+	HMACSecret    string              `json:"hmac_secret"`
+	PublicKeyFile string              `json:"public_key_file"`
+	JWKSFile      string              `json:"jwks_file"`
+	Issuer        string              `json:"issuer"`
+	Audience      string              `json:"audience"`
+	Leeway        int                 `json:"leeway"`
+	ScopeMap      map[string][]string `json:"scope_map"`
+}
+
+// APIKeyConfig describes a consumer API key; only the SHA-256 hash of the key is stored
+type APIKeyConfig struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Hash   string   `json:"hash"`
+	Tier   string   `json:"tier"`
+	Scopes []string `json:"scopes"`
+}
+
+// RateLimitConfig holds per-client rate limiting configuration
+type RateLimitConfig struct {
+	Enabled        bool                     `json:"enabled"`
+	DefaultTier    string                   `json:"default_tier"`
+	AnonymousTier  string                   `json:"anonymous_tier"`
+	Tiers          map[string]RateLimitTier `json:"tiers"`
+	TrustedProxies []string                 `json:"trusted_proxies"`
+	MaxEntries     int                      `json:"max_entries"`
+	IdleTimeout    int                      `json:"idle_timeout"`
+}
+
+// RateLimitTier holds the token bucket settings for a class of clients
+type RateLimitTier struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	Burst             int `json:"burst"`
+}
+
+// QuotaConfig holds the upstream API call budget
+type QuotaConfig struct {
+	Enabled           bool `json:"enabled"`
+	PerMinute         int  `json:"per_minute"`
+	PerDay            int  `json:"per_day"`
+	BackgroundReserve int  `json:"background_reserve"`
+}
+
+// BreakerConfig holds the circuit breaker around upstream calls
+type BreakerConfig struct {
+	Enabled          bool `json:"enabled"`
+	FailureThreshold int  `json:"failure_threshold"`
+	Cooldown         int  `json:"cooldown"`
+}
+
+// CacheConfig holds upstream response cache configuration
+type CacheConfig struct {
+	Enabled     bool        `json:"enabled"`
+	Backend     string      `json:"backend"`
+	WeatherTTL  int         `json:"weather_ttl"`
+	ForecastTTL int         `json:"forecast_ttl"`
+	MaxStale    int         `json:"max_stale"`
+	Redis       RedisConfig `json:"redis"`
+}
+
+// RedisConfig holds the connection settings for the shared Redis cache
+type RedisConfig struct {
+	Address   string `json:"address"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"key_prefix"`
+	TLS       bool   `json:"tls"`
+}
+
+// StreamConfig holds live weather update stream configuration
+type StreamConfig struct {
+	MaxConnections          int `json:"max_connections"`
+	MaxCities               int `json:"max_cities"`
+	RefreshInterval         int `json:"refresh_interval"`
+	ForecastRefreshInterval int `json:"forecast_refresh_interval"`
+	HeartbeatInterval       int `json:"heartbeat_interval"`
+}
+
+// StorageConfig holds historical observation storage configuration
+type StorageConfig struct {
+	Enabled       bool   `json:"enabled"`
+	Driver        string `json:"driver"`
+	Path          string `json:"path"`
+	RetentionDays int    `json:"retention_days"`
+}
+
+// HistoryConfig holds historical query API configuration
+type HistoryConfig struct {
+	MaxRangeDays    int `json:"max_range_days"`
+	DefaultPageSize int `json:"default_page_size"`
+	MaxPageSize     int `json:"max_page_size"`
+}
+
+// CollectorConfig holds background collection configuration
+type CollectorConfig struct {
+	Enabled           bool     `json:"enabled"`
+	Schedule          string   `json:"schedule"`
+	Cities            []string `json:"cities"`
+	CitiesFile        string   `json:"cities_file"`
+	RequestsPerMinute int      `json:"requests_per_minute"`
+}
+
+// MetricsConfig holds Prometheus metrics configuration
+type MetricsConfig struct {
+	Enabled bool `json:"enabled"`
 }
 
-// LoadConfig loads configuration from file and environment variables
-func LoadConfig(configPath string) (*Config, error) {
+// LogConfig holds logging configuration
+type LogConfig struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+// HealthConfig holds readiness check configuration
+type HealthConfig struct {
+	UpstreamProbe bool   `json:"upstream_probe"`
+	ProbeCity     string `json:"probe_city"`
+	ProbeInterval int    `json:"probe_interval"`
+	CheckTimeout  int    `json:"check_timeout"`
+}
+
+// AdminConfig holds the separate listener for operational endpoints
+type AdminConfig struct {
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"`
+	Token   string `json:"token"`
+	Pprof   bool   `json:"pprof"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Enabled     bool    `json:"enabled"`
+	Exporter    string  `json:"exporter"`
+	Endpoint    string  `json:"endpoint"`
+	ServiceName string  `json:"service_name"`
+	SampleRatio float64 `json:"sample_ratio"`
+
+	// PropagateUpstream sends trace context headers to OpenWeatherMap, a
+	// third party, so it is off unless explicitly enabled
+	PropagateUpstream bool `json:"propagate_upstream"`
+}
+
+// LoadConfig loads configuration with increasing precedence from defaults,
+// the config file, environment variables and finally overrides, which the
+// command line uses for flags
+func LoadConfig(configPath string, overrides ...func(*Config)) (*Config, error) {
 	// Default configuration
 	config := &Config{
 		Server: ServerConfig{
 			Port:         "8080",
-			Host:         "localhost",
+			Host:         "",
 			ReadTimeout:  30,
 			WriteTimeout: 30,
 			CORS: CORSConfig{
@@ -54,10 +257,112 @@ func LoadConfig(configPath string) (*Config, error) {
 				AllowedHeaders:   []string{"*"},
 				AllowCredentials: true,
 			},
+			TLS: TLSConfig{
+				MinVersion:   "1.2",
+				CipherPolicy: "default",
+				ClientAuth:   "none",
+				HTTP2:        true,
+			},
+			Compression: CompressionConfig{
+				Enabled: true,
+				MinSize: 1024,
+			},
 		},
 		API: APIConfig{
-			BaseURL: "https://api.openweathermap.org/data/2.5",
-			Timeout: 30,
+			BaseURL:    "https://api.openweathermap.org/data/2.5",
+			AlertsURL:  "https://api.openweathermap.org/data/3.0/onecall",
+			Timeout:    30,
+			MaxRetries: 2,
+		},
+		Auth: AuthConfig{
+			Enabled:    false,
+			HeaderName: "X-API-Key",
+			QueryParam: "api_key",
+			JWT: JWTConfig{
+				Algorithms: []string{"RS256", "ES256"},
+				Leeway:     30,
+			},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:       false,
+			DefaultTier:   "standard",
+			AnonymousTier: "anonymous",
+			Tiers: map[string]RateLimitTier{
+				"anonymous": {RequestsPerMinute: 30, Burst: 10},
+				"standard":  {RequestsPerMinute: 120, Burst: 30},
+				"premium":   {RequestsPerMinute: 600, Burst: 100},
+			},
+			MaxEntries:  10000,
+			IdleTimeout: 600,
+		},
+		Quota: QuotaConfig{
+			Enabled:           true,
+			PerMinute:         60,
+			PerDay:            30000,
+			BackgroundReserve: 20,
+		},
+		Breaker: BreakerConfig{
+			Enabled:          true,
+			FailureThreshold: 5,
+			Cooldown:         30,
+		},
+		Cache: CacheConfig{
+			Enabled:     true,
+			Backend:     "memory",
+			WeatherTTL:  300,
+			ForecastTTL: 1800,
+			MaxStale:    3600,
+			Redis: RedisConfig{
+				Address:   "localhost:6379",
+				KeyPrefix: "weather-api:",
+			},
+		},
+		Stream: StreamConfig{
+			MaxConnections:          100,
+			MaxCities:               10,
+			RefreshInterval:         60,
+			ForecastRefreshInterval: 900,
+			HeartbeatInterval:       15,
+		},
+		Storage: StorageConfig{
+			Enabled:       false,
+			Driver:        "bolt",
+			Path:          "weather.db",
+			RetentionDays: 90,
+		},
+		History: HistoryConfig{
+			MaxRangeDays:    366,
+			DefaultPageSize: 100,
+			MaxPageSize:     1000,
+		},
+		Collector: CollectorConfig{
+			Enabled:           false,
+			Schedule:          "*/15 * * * *",
+			RequestsPerMinute: 50,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Health: HealthConfig{
+			UpstreamProbe: false,
+			ProbeCity:     "London",
+			ProbeInterval: 60,
+			CheckTimeout:  5,
+		},
+		Admin: AdminConfig{
+			Enabled: true,
+			Address: "127.0.0.1:9090",
+			Pprof:   true,
+		},
+		Tracing: TracingConfig{
+			Enabled:     false,
+			Exporter:    "otlp",
+			ServiceName: "weather-api",
+			SampleRatio: 1,
 		},
 	}
 
@@ -67,41 +372,70 @@ func LoadConfig(configPath string) (*Config, error) {
 			return nil, fmt.Errorf("failed to load config from file: %v", err)
 		}
 	} else {
-		if os.Getenv("OPENWEATHER_API_KEY") == "" {
+		apiKey, err := secretEnv("OPENWEATHER_API_KEY")
+		if err != nil {
+			return nil, err
+		}
+		if apiKey == "" {
 			if err := loadLegacyAPIConfig(config); err != nil {
 				return nil, fmt.Errorf("failed to load API config: %v", err)
 			}
 		}
 	}
 
-	// Override with environment variables
-	loadFromEnv(config)
+	// Override with environment variables, collecting unparseable values
+	// so they are reported together with validation problems
+	var p problems
+	loadFromEnv(config, &p)
+
+	// Apply command-line overrides last
+	for _, override := range overrides {
+		override(config)
+	}
 
-	// Validate required fields
-	if err := validateConfig(config); err != nil {
+	validateConfig(config, &p)
+	if err := p.err(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %v", err)
 	}
 
 	return config, nil
 }
 
+// loadFromFile overlays a JSON, YAML or TOML file, chosen by extension, onto
+// config. YAML and TOML documents use the same keys as JSON: they are decoded
+// generically and re-encoded as JSON so every format shares the json tags.
 func loadFromFile(config *Config, path string) error {
-	// If no path provided, try to load legacy .apiConfig file
-	if path == "" {
-		return loadLegacyAPIConfig(config)
-	}
-
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		// Try to load legacy .apiConfig file
-		return loadLegacyAPIConfig(config)
-	}
-
 	bytes, err := ioutil.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	return json.Unmarshal(bytes, config)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(bytes, &doc); err != nil {
+			return fmt.Errorf("invalid YAML: %v", err)
+		}
+		if bytes, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	case ".toml":
+		var doc map[string]interface{}
+		if err := toml.Unmarshal(bytes, &doc); err != nil {
+			return fmt.Errorf("invalid TOML: %v", err)
+		}
+		if bytes, err = json.Marshal(doc); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q (use .json, .yaml, .yml or .toml)", filepath.Ext(path))
+	}
+
+	// Unknown keys are rejected so misspelled settings don't silently fall back to defaults
+	decoder := json.NewDecoder(strings.NewReader(string(bytes)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(config)
 }
 
 func loadLegacyAPIConfig(config *Config) error {
@@ -123,7 +457,9 @@ func loadLegacyAPIConfig(config *Config) error {
 	return nil
 }
 
-func loadFromEnv(config *Config) {
+// loadFromEnv overlays environment variables onto config, recording values
+// that cannot be parsed as problems
+func loadFromEnv(config *Config, p *problems) {
 	// Server configuration from environment
 	if port := os.Getenv("PORT"); port != "" {
 		config.Server.Port = port
@@ -131,16 +467,8 @@ func loadFromEnv(config *Config) {
 	if host := os.Getenv("HOST"); host != "" {
 		config.Server.Host = host
 	}
-	if timeout := os.Getenv("READ_TIMEOUT"); timeout != "" {
-		if val, err := strconv.Atoi(timeout); err == nil {
-			config.Server.ReadTimeout = val
-		}
-	}
-	if timeout := os.Getenv("WRITE_TIMEOUT"); timeout != "" {
-		if val, err := strconv.Atoi(timeout); err == nil {
-			config.Server.WriteTimeout = val
-		}
-	}
+	p.envInt("READ_TIMEOUT", &config.Server.ReadTimeout)
+	p.envInt("WRITE_TIMEOUT", &config.Server.WriteTimeout)
 
 	if origins := os.Getenv("ALLOWED_ORIGINS"); origins != "" {
 		parts := strings.Split(origins, ",")
@@ -150,28 +478,210 @@ func loadFromEnv(config *Config) {
 		config.Server.CORS.AllowedOrigins = parts
 	}
 
+	// TLS configuration from environment
+	p.envBool("TLS_ENABLED", &config.Server.TLS.Enabled)
+	if path := os.Getenv("TLS_CERT_FILE"); path != "" {
+		config.Server.TLS.CertFile = path
+	}
+	if path := os.Getenv("TLS_KEY_FILE"); path != "" {
+		config.Server.TLS.KeyFile = path
+	}
+	if version := os.Getenv("TLS_MIN_VERSION"); version != "" {
+		config.Server.TLS.MinVersion = version
+	}
+	if policy := os.Getenv("TLS_CIPHER_POLICY"); policy != "" {
+		config.Server.TLS.CipherPolicy = policy
+	}
+	if path := os.Getenv("TLS_CLIENT_CA_FILE"); path != "" {
+		config.Server.TLS.ClientCAFile = path
+	}
+	if mode := os.Getenv("TLS_CLIENT_AUTH"); mode != "" {
+		config.Server.TLS.ClientAuth = mode
+	}
+	p.envBool("TLS_HTTP2", &config.Server.TLS.HTTP2)
+	if port := os.Getenv("TLS_REDIRECT_PORT"); port != "" {
+		config.Server.TLS.RedirectPort = port
+	}
+
+	// Compression configuration from environment
+	p.envBool("COMPRESSION_ENABLED", &config.Server.Compression.Enabled)
+	p.envInt("COMPRESSION_MIN_SIZE", &config.Server.Compression.MinSize)
+
 	// API configuration from environment
-	if apiKey := os.Getenv("OPENWEATHER_API_KEY"); apiKey != "" {
+	if apiKey, err := secretEnv("OPENWEATHER_API_KEY"); err != nil {
+		p.add("OPENWEATHER_API_KEY", "%v", err)
+	} else if apiKey != "" {
 		config.API.OpenWeatherMapApiKey = apiKey
 	}
 	if baseURL := os.Getenv("OPENWEATHER_BASE_URL"); baseURL != "" {
 		config.API.BaseURL = baseURL
 	}
-	if timeout := os.Getenv("API_TIMEOUT"); timeout != "" {
-		if val, err := strconv.Atoi(timeout); err == nil {
-			config.API.Timeout = val
+	if alertsURL := os.Getenv("OPENWEATHER_ALERTS_URL"); alertsURL != "" {
+		config.API.AlertsURL = alertsURL
+	}
+	p.envInt("API_TIMEOUT", &config.API.Timeout)
+	p.envInt("API_MAX_RETRIES", &config.API.MaxRetries)
+
+	// Auth configuration from environment
+	p.envBool("AUTH_ENABLED", &config.Auth.Enabled)
+	if path := os.Getenv("AUTH_KEYS_FILE"); path != "" {
+		config.Auth.KeysFile = path
+	}
+	p.envBool("JWT_ENABLED", &config.Auth.JWT.Enabled)
+	if scopes := os.Getenv("AUTH_ROUTE_SCOPES"); scopes != "" {
+		config.Auth.RouteScopes = make(map[string]string)
+		for _, pair := range strings.Split(scopes, ",") {
+			route, scope, found := strings.Cut(strings.TrimSpace(pair), "=")
+			if found {
+				config.Auth.RouteScopes[strings.TrimSpace(route)] = strings.TrimSpace(scope)
+			}
 		}
 	}
-}
+	if algorithms := os.Getenv("JWT_ALGORITHMS"); algorithms != "" {
+		parts := strings.Split(algorithms, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		config.Auth.JWT.Algorithms = parts
+	}
+	if secret, err := secretEnv("JWT_HMAC_SECRET"); err != nil {
+		p.add("JWT_HMAC_SECRET", "%v", err)
+	} else if secret != "" {
+		config.Auth.JWT.HMACSecret = secret
+	}
+	if path := os.Getenv("JWT_PUBLIC_KEY_FILE"); path != "" {
+		config.Auth.JWT.PublicKeyFile = path
+	}
+	if path := os.Getenv("JWT_JWKS_FILE"); path != "" {
+		config.Auth.JWT.JWKSFile = path
+	}
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		config.Auth.JWT.Issuer = issuer
+	}
+	if audience := os.Getenv("JWT_AUDIENCE"); audience != "" {
+		config.Auth.JWT.Audience = audience
+	}
 
-func validateConfig(config *Config) error {
-	if config.API.OpenWeatherMapApiKey == "" {
-		return fmt.Errorf("OpenWeatherMap API key is required")
+	// Rate limit configuration from environment
+	p.envBool("RATE_LIMIT_ENABLED", &config.RateLimit.Enabled)
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		parts := strings.Split(proxies, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		config.RateLimit.TrustedProxies = parts
 	}
-	if config.Server.Port == "" {
-		return fmt.Errorf("server port is required")
+
+	// Quota configuration from environment
+	p.envBool("QUOTA_ENABLED", &config.Quota.Enabled)
+	p.envInt("QUOTA_PER_MINUTE", &config.Quota.PerMinute)
+	p.envInt("QUOTA_PER_DAY", &config.Quota.PerDay)
+
+	// Circuit breaker configuration from environment
+	p.envBool("BREAKER_ENABLED", &config.Breaker.Enabled)
+	p.envInt("BREAKER_FAILURE_THRESHOLD", &config.Breaker.FailureThreshold)
+	p.envInt("BREAKER_COOLDOWN", &config.Breaker.Cooldown)
+
+	// Cache configuration from environment
+	p.envBool("CACHE_ENABLED", &config.Cache.Enabled)
+	p.envInt("CACHE_WEATHER_TTL", &config.Cache.WeatherTTL)
+	p.envInt("CACHE_FORECAST_TTL", &config.Cache.ForecastTTL)
+	p.envInt("CACHE_MAX_STALE", &config.Cache.MaxStale)
+	if backend := os.Getenv("CACHE_BACKEND"); backend != "" {
+		config.Cache.Backend = backend
 	}
-	return nil
+	if address := os.Getenv("REDIS_ADDRESS"); address != "" {
+		config.Cache.Redis.Address = address
+	}
+	if password, err := secretEnv("REDIS_PASSWORD"); err != nil {
+		p.add("REDIS_PASSWORD", "%v", err)
+	} else if password != "" {
+		config.Cache.Redis.Password = password
+	}
+	p.envInt("REDIS_DB", &config.Cache.Redis.DB)
+	if prefix := os.Getenv("REDIS_KEY_PREFIX"); prefix != "" {
+		config.Cache.Redis.KeyPrefix = prefix
+	}
+	p.envBool("REDIS_TLS", &config.Cache.Redis.TLS)
+
+	// Stream configuration from environment
+	p.envInt("STREAM_MAX_CONNECTIONS", &config.Stream.MaxConnections)
+	p.envInt("STREAM_MAX_CITIES", &config.Stream.MaxCities)
+	p.envInt("STREAM_REFRESH_INTERVAL", &config.Stream.RefreshInterval)
+	p.envInt("STREAM_FORECAST_REFRESH_INTERVAL", &config.Stream.ForecastRefreshInterval)
+	p.envInt("STREAM_HEARTBEAT_INTERVAL", &config.Stream.HeartbeatInterval)
+
+	// Storage configuration from environment
+	p.envBool("STORAGE_ENABLED", &config.Storage.Enabled)
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" {
+		config.Storage.Driver = driver
+	}
+	if path := os.Getenv("STORAGE_PATH"); path != "" {
+		config.Storage.Path = path
+	}
+	p.envInt("STORAGE_RETENTION_DAYS", &config.Storage.RetentionDays)
+
+	// History configuration from environment
+	p.envInt("HISTORY_MAX_RANGE_DAYS", &config.History.MaxRangeDays)
+	p.envInt("HISTORY_MAX_PAGE_SIZE", &config.History.MaxPageSize)
+
+	// Collector configuration from environment
+	p.envBool("COLLECTOR_ENABLED", &config.Collector.Enabled)
+	if schedule := os.Getenv("COLLECTOR_SCHEDULE"); schedule != "" {
+		config.Collector.Schedule = schedule
+	}
+	if cities := os.Getenv("COLLECTOR_CITIES"); cities != "" {
+		parts := strings.Split(cities, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		config.Collector.Cities = parts
+	}
+	if path := os.Getenv("COLLECTOR_CITIES_FILE"); path != "" {
+		config.Collector.CitiesFile = path
+	}
+	p.envInt("COLLECTOR_REQUESTS_PER_MINUTE", &config.Collector.RequestsPerMinute)
+
+	// Metrics configuration from environment
+	p.envBool("METRICS_ENABLED", &config.Metrics.Enabled)
+
+	// Log configuration from environment
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		config.Log.Level = level
+	}
+	if format := os.Getenv("LOG_FORMAT"); format != "" {
+		config.Log.Format = format
+	}
+
+	// Tracing configuration from environment
+	p.envBool("TRACING_ENABLED", &config.Tracing.Enabled)
+	if exporter := os.Getenv("TRACING_EXPORTER"); exporter != "" {
+		config.Tracing.Exporter = exporter
+	}
+	if endpoint := os.Getenv("TRACING_ENDPOINT"); endpoint != "" {
+		config.Tracing.Endpoint = endpoint
+	}
+	p.envFloat("TRACING_SAMPLE_RATIO", &config.Tracing.SampleRatio)
+	p.envBool("TRACING_PROPAGATE_UPSTREAM", &config.Tracing.PropagateUpstream)
+
+	// Health check configuration from environment
+	p.envBool("HEALTH_UPSTREAM_PROBE", &config.Health.UpstreamProbe)
+	if city := os.Getenv("HEALTH_PROBE_CITY"); city != "" {
+		config.Health.ProbeCity = city
+	}
+	p.envInt("HEALTH_PROBE_INTERVAL", &config.Health.ProbeInterval)
+
+	// Admin listener configuration from environment
+	p.envBool("ADMIN_ENABLED", &config.Admin.Enabled)
+	if address := os.Getenv("ADMIN_ADDRESS"); address != "" {
+		config.Admin.Address = address
+	}
+	if token, err := secretEnv("ADMIN_TOKEN"); err != nil {
+		p.add("ADMIN_TOKEN", "%v", err)
+	} else if token != "" {
+		config.Admin.Token = token
+	}
+	p.envBool("ADMIN_PPROF", &config.Admin.Pprof)
 }
 
 // GetAddress returns the server address
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// configFiles holds the same settings in each supported file format
+var configFiles = map[string]string{
+	".yaml": `
+api:
+  openWeatherMapApiKey: file-key
+server:
+  port: "7001"
+  read_timeout: 11
+log:
+  level: warn
+`,
+	".toml": `
+[api]
+openWeatherMapApiKey = "file-key"
+
+[server]
+port = "7001"
+read_timeout = 11
+
+[log]
+level = "warn"
+`,
+}
+
+// isolateEnv clears the environment variables these tests set, so values
+// from the developer's shell cannot leak into the layers under test
+func isolateEnv(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"OPENWEATHER_API_KEY", "OPENWEATHER_API_KEY_FILE",
+		"PORT", "READ_TIMEOUT", "LOG_LEVEL",
+	} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeConfigFile(t *testing.T, ext string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config"+ext)
+	if err := os.WriteFile(path, []byte(configFiles[ext]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     bool
+		env      map[string]string
+		flagPort string
+		wantPort string
+	}{
+		{"default", false, nil, "", "8080"},
+		{"file over default", true, nil, "", "7001"},
+		{"env over default", false, map[string]string{"PORT": "7002"}, "", "7002"},
+		{"env over file", true, map[string]string{"PORT": "7002"}, "", "7002"},
+		{"flag over default", false, nil, "7003", "7003"},
+		{"flag over file", true, nil, "7003", "7003"},
+		{"flag over env and file", true, map[string]string{"PORT": "7002"}, "7003", "7003"},
+	}
+
+	for ext := range configFiles {
+		for _, tt := range tests {
+			t.Run(ext+"/"+tt.name, func(t *testing.T) {
+				isolateEnv(t)
+				// Without a file the API key must come from the environment
+				t.Setenv("OPENWEATHER_API_KEY", "env-key")
+				for name, value := range tt.env {
+					t.Setenv(name, value)
+				}
+
+				var path string
+				if tt.file {
+					path = writeConfigFile(t, ext)
+				}
+				var overrides []func(*Config)
+				if tt.flagPort != "" {
+					overrides = append(overrides, func(cfg *Config) { cfg.Server.Port = tt.flagPort })
+				}
+
+				cfg, err := LoadConfig(path, overrides...)
+				if err != nil {
+					t.Fatalf("LoadConfig: %v", err)
+				}
+				if cfg.Server.Port != tt.wantPort {
+					t.Errorf("port = %q, want %q", cfg.Server.Port, tt.wantPort)
+				}
+			})
+		}
+	}
+}
+
+// TestLoadConfigLayers sets a different setting at each layer and checks
+// they combine rather than one layer replacing another wholesale
+func TestLoadConfigLayers(t *testing.T) {
+	for ext := range configFiles {
+		t.Run(ext, func(t *testing.T) {
+			isolateEnv(t)
+			t.Setenv("LOG_LEVEL", "error")
+			t.Setenv("PORT", "7002")
+
+			cfg, err := LoadConfig(writeConfigFile(t, ext), func(cfg *Config) { cfg.Server.Port = "7003" })
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+
+			if cfg.Server.Port != "7003" {
+				t.Errorf("server.port = %q, want the flag value 7003", cfg.Server.Port)
+			}
+			if cfg.Log.Level != "error" {
+				t.Errorf("log.level = %q, want the env value error", cfg.Log.Level)
+			}
+			if cfg.Server.ReadTimeout != 11 {
+				t.Errorf("server.read_timeout = %d, want the file value 11", cfg.Server.ReadTimeout)
+			}
+			if cfg.API.OpenWeatherMapApiKey != "file-key" {
+				t.Errorf("api key = %q, want the file value", cfg.API.OpenWeatherMapApiKey)
+			}
+			if cfg.Server.WriteTimeout != 30 {
+				t.Errorf("server.write_timeout = %d, want the default 30", cfg.Server.WriteTimeout)
+			}
+		})
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	isolateEnv(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("server:\n  prot: \"7001\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("misspelled key was accepted")
+	}
+}
+
+func TestLoadConfigReportsAllProblems(t *testing.T) {
+	isolateEnv(t)
+	t.Setenv("OPENWEATHER_API_KEY", "env-key")
+	t.Setenv("PORT", "not-a-port")
+	t.Setenv("READ_TIMEOUT", "soon")
+	t.Setenv("LOG_LEVEL", "loud")
+
+	_, err := LoadConfig("")
+	if err == nil {
+		t.Fatal("invalid configuration was accepted")
+	}
+	for _, want := range []string{"3 problems", "server.port", "READ_TIMEOUT", "log.level"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestValidateAPIKeyHashes(t *testing.T) {
+	const hash = "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+	tests := []struct {
+		hash  string
+		valid bool
+	}{
+		{hash, true},
+		{"sha256:" + hash, true},
+		{"sha256:" + hash[:63], false},
+		{"md5:" + hash, false},
+		{"not-hex", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			isolateEnv(t)
+			t.Setenv("OPENWEATHER_API_KEY", "env-key")
+			_, err := LoadConfig("", func(cfg *Config) {
+				cfg.Auth.Enabled = true
+				cfg.Auth.APIKeys = []APIKeyConfig{{ID: "dashboard", Hash: tt.hash}}
+			})
+			if tt.valid && err != nil {
+				t.Errorf("rejected a valid hash: %v", err)
+			}
+			if !tt.valid && (err == nil || !strings.Contains(err.Error(), "auth.api_keys[0].hash")) {
+				t.Errorf("error = %v, want a problem for auth.api_keys[0].hash", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// MergeReloadable returns a copy of current with the settings that can change
+// while the server runs taken from next: CORS, the upstream API key, cache
+// TTLs, rate limit tiers and trusted proxies, and the log level. It also
+// returns the paths of any other settings that differ, which only take effect
+// after a restart.
+func MergeReloadable(current, next *Config) (*Config, []string) {
+	merged := *current
+
+	merged.Server.CORS = next.Server.CORS
+	merged.API.OpenWeatherMapApiKey = next.API.OpenWeatherMapApiKey
+	merged.Cache.WeatherTTL = next.Cache.WeatherTTL
+	merged.Cache.ForecastTTL = next.Cache.ForecastTTL
+	merged.RateLimit.Tiers = next.RateLimit.Tiers
+	merged.RateLimit.DefaultTier = next.RateLimit.DefaultTier
+	merged.RateLimit.AnonymousTier = next.RateLimit.AnonymousTier
+	merged.RateLimit.TrustedProxies = next.RateLimit.TrustedProxies
+	merged.Log.Level = next.Log.Level
+
+	return &merged, diffFields(reflect.ValueOf(merged), reflect.ValueOf(*next), "")
+}
+
+// diffFields lists the json paths of struct fields whose values differ
+func diffFields(a, b reflect.Value, prefix string) []string {
+	var changed []string
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			changed = append(changed, diffFields(a.Field(i), b.Field(i), name)...)
+			continue
+		}
+		if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeReloadable(t *testing.T) {
+	current := &Config{
+		Server: ServerConfig{Port: "8080", CORS: CORSConfig{AllowedOrigins: []string{"https://a.example"}}},
+		API:    APIConfig{OpenWeatherMapApiKey: "old-key", BaseURL: "https://api.example"},
+		Cache:  CacheConfig{Enabled: true, WeatherTTL: 600},
+		RateLimit: RateLimitConfig{
+			DefaultTier: "standard",
+			Tiers:       map[string]RateLimitTier{"standard": {RequestsPerMinute: 60}},
+		},
+		Storage: StorageConfig{Path: "weather.db"},
+		Log:     LogConfig{Level: "info", Format: "json"},
+	}
+
+	next := *current
+	next.Server.Port = "9090"
+	next.Server.CORS = CORSConfig{AllowedOrigins: []string{"https://b.example"}}
+	next.API.OpenWeatherMapApiKey = "new-key"
+	next.Cache.WeatherTTL = 300
+	next.RateLimit.Tiers = map[string]RateLimitTier{"standard": {RequestsPerMinute: 120}}
+	next.Storage.Path = "other.db"
+	next.Log.Level = "debug"
+
+	merged, restart := MergeReloadable(current, &next)
+
+	if merged.Server.Port != "8080" || merged.Storage.Path != "weather.db" {
+		t.Errorf("restart-only settings changed: port %s, storage path %s", merged.Server.Port, merged.Storage.Path)
+	}
+	if merged.API.OpenWeatherMapApiKey != "new-key" || merged.Cache.WeatherTTL != 300 || merged.Log.Level != "debug" {
+		t.Errorf("reloadable settings not applied: %+v", merged)
+	}
+	if !reflect.DeepEqual(merged.Server.CORS, next.Server.CORS) || !reflect.DeepEqual(merged.RateLimit.Tiers, next.RateLimit.Tiers) {
+		t.Errorf("CORS or rate limit tiers not applied")
+	}
+	if current.Log.Level != "info" || current.API.OpenWeatherMapApiKey != "old-key" {
+		t.Errorf("current config was modified")
+	}
+
+	want := []string{"server.port", "storage.path"}
+	if !reflect.DeepEqual(restart, want) {
+		t.Errorf("restart-only changes = %v, want %v", restart, want)
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	base := Config{
+		Server: ServerConfig{Port: "8080", CORS: CORSConfig{AllowedOrigins: []string{"*"}}},
+		Log:    LogConfig{Level: "info"},
+	}
+
+	tests := []struct {
+		name   string
+		change func(c *Config)
+		want   []string
+	}{
+		{"identical", func(c *Config) {}, nil},
+		{"top level section", func(c *Config) { c.Log.Format = "text" }, []string{"log.format"}},
+		{"nested section", func(c *Config) { c.Server.CORS.AllowCredentials = true }, []string{"server.cors.allow_credentials"}},
+		{"slice", func(c *Config) { c.Server.CORS.AllowedOrigins = []string{"*", "https://a.example"} }, []string{"server.cors.allowed_origins"}},
+		{"several", func(c *Config) { c.Server.Port = "9090"; c.Log.Level = "debug" }, []string{"server.port", "log.level"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			other.Server.CORS.AllowedOrigins = append([]string(nil), base.Server.CORS.AllowedOrigins...)
+			tt.change(&other)
+
+			got := diffFields(reflect.ValueOf(base), reflect.ValueOf(other), "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffFields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
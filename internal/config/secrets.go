@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// redactedValue replaces secrets in configuration dumps
+const redactedValue = "REDACTED"
+
+// secretEnv returns the value of a secret environment variable. The value
+// may instead be read from the file named by NAME_FILE, as provided by Docker
+// and Kubernetes secrets; the file wins when both are set.
+func secretEnv(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %v", name, err)
+	}
+	return strings.TrimSpace(string(bytes)), nil
+}
+
+// Redacted returns a deep copy of the configuration with secrets masked,
+// suitable for logging or admin endpoints
+func (c *Config) Redacted() (*Config, error) {
+	bytes, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var copied Config
+	if err := json.Unmarshal(bytes, &copied); err != nil {
+		return nil, err
+	}
+
+	copied.API.OpenWeatherMapApiKey = redact(copied.API.OpenWeatherMapApiKey)
+	copied.Auth.JWT.HMACSecret = redact(copied.Auth.JWT.HMACSecret)
+	copied.Admin.Token = redact(copied.Admin.Token)
+	copied.Cache.Redis.Password = redact(copied.Cache.Redis.Password)
+	for i := range copied.Auth.APIKeys {
+		copied.Auth.APIKeys[i].Hash = redact(copied.Auth.APIKeys[i].Hash)
+	}
+	return &copied, nil
+}
+
+// redact masks a non-empty secret, leaving empty values visible so unset secrets can be spotted
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
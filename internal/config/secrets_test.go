@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretEnv(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		file    string
+		want    string
+		wantErr string
+	}{
+		{"unset", "", "", "", ""},
+		{"environment", "from-env", "", "from-env", ""},
+		{"file with trailing newline", "", secretFile, "from-file", ""},
+		{"file wins over environment", "from-env", secretFile, "from-file", ""},
+		{"missing file", "from-env", filepath.Join(dir, "missing"), "", "failed to read TEST_SECRET_FILE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", tt.value)
+			t.Setenv("TEST_SECRET_FILE", tt.file)
+
+			got, err := secretEnv("TEST_SECRET")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("secretEnv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		API: APIConfig{OpenWeatherMapApiKey: "owm-key", BaseURL: "https://api.example"},
+		Auth: AuthConfig{
+			APIKeys: []APIKeyConfig{{ID: "portal", Hash: "abc123"}, {ID: "mobile", Hash: "def456"}},
+			JWT:     JWTConfig{HMACSecret: "hmac-secret", Issuer: "https://issuer.example"},
+		},
+		Cache: CacheConfig{Redis: RedisConfig{Address: "localhost:6379", Password: "redis-password"}},
+		Admin: AdminConfig{Address: "127.0.0.1:9090", Token: "admin-token"},
+	}
+
+	redacted, err := cfg.Redacted()
+	if err != nil {
+		t.Fatalf("Redacted: %v", err)
+	}
+
+	dump, err := json.Marshal(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"owm-key", "abc123", "def456", "hmac-secret", "redis-password", "admin-token"} {
+		if strings.Contains(string(dump), secret) {
+			t.Errorf("redacted config contains %q", secret)
+		}
+	}
+	if strings.Count(string(dump), redactedValue) != 6 {
+		t.Errorf("redacted config masks %d values, want 6: %s", strings.Count(string(dump), redactedValue), dump)
+	}
+
+	// Other settings stay visible and the original is untouched
+	if redacted.API.BaseURL != cfg.API.BaseURL || redacted.Auth.JWT.Issuer != cfg.Auth.JWT.Issuer ||
+		redacted.Cache.Redis.Address != cfg.Cache.Redis.Address || redacted.Admin.Address != cfg.Admin.Address ||
+		redacted.Auth.APIKeys[0].ID != "portal" {
+		t.Errorf("non-secret settings changed: %+v", redacted)
+	}
+	if cfg.API.OpenWeatherMapApiKey != "owm-key" || cfg.Auth.APIKeys[0].Hash != "abc123" {
+		t.Error("Redacted modified the original config")
+	}
+}
+
+func TestRedactedLeavesUnsetSecretsEmpty(t *testing.T) {
+	redacted, err := (&Config{}).Redacted()
+	if err != nil {
+		t.Fatalf("Redacted: %v", err)
+	}
+	if redacted.API.OpenWeatherMapApiKey != "" || redacted.Auth.JWT.HMACSecret != "" ||
+		redacted.Cache.Redis.Password != "" || redacted.Admin.Token != "" {
+		t.Errorf("unset secrets were masked: %+v", redacted)
+	}
+}
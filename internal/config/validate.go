@@ -0,0 +1,357 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Problem is a single invalid setting, identified by its json path in the
+// config file or by the environment variable it came from
+type Problem struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists every problem found while loading configuration
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("%s: %s", e.Problems[0].Field, e.Problems[0].Message)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d problems:", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Field, p.Message)
+	}
+	return b.String()
+}
+
+// problems collects configuration problems so they can be reported together
+type problems struct {
+	list []Problem
+}
+
+func (p *problems) add(field, format string, args ...interface{}) {
+	p.list = append(p.list, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check records a problem for field unless ok holds
+func (p *problems) check(ok bool, field, format string, args ...interface{}) {
+	if !ok {
+		p.add(field, format, args...)
+	}
+}
+
+// err returns the collected problems as a *ValidationError, or nil if there are none
+func (p *problems) err() error {
+	if len(p.list) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: p.list}
+}
+
+// envInt sets dst from the integer environment variable name, if set
+func (p *problems) envInt(name string, dst *int) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		p.add(name, "%q is not an integer", raw)
+		return
+	}
+	*dst = val
+}
+
+// envBool sets dst from the boolean environment variable name, if set
+func (p *problems) envBool(name string, dst *bool) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		p.add(name, "%q is not a boolean", raw)
+		return
+	}
+	*dst = val
+}
+
+// envFloat sets dst from the numeric environment variable name, if set
+func (p *problems) envFloat(name string, dst *float64) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return
+	}
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		p.add(name, "%q is not a number", raw)
+		return
+	}
+	*dst = val
+}
+
+// externalCheck validates a setting whose valid values are only known to
+// the package that interprets it
+type externalCheck struct {
+	field string
+	check func(*Config) error
+}
+
+var (
+	checksMu sync.RWMutex
+	checks   []externalCheck
+)
+
+// RegisterCheck adds a check for a setting owned by another package, such as
+// a collector schedule or a storage driver name. Packages register their
+// checks from init so the problems they find are reported together with all
+// others by LoadConfig.
+func RegisterCheck(field string, check func(*Config) error) {
+	checksMu.Lock()
+	defer checksMu.Unlock()
+
+	checks = append(checks, externalCheck{field: field, check: check})
+}
+
+// jwtAlgorithms are the signing algorithms the auth package can verify
+var jwtAlgorithms = map[string]bool{"HS256": true, "RS256": true, "ES256": true}
+
+// validateConfig checks every setting and records all problems found
+func validateConfig(config *Config, p *problems) {
+	server := config.Server
+	port, err := strconv.Atoi(server.Port)
+	p.check(err == nil && port > 0 && port <= 65535, "server.port", "must be a port number between 1 and 65535, got %q", server.Port)
+	p.check(server.ReadTimeout >= 0, "server.read_timeout", "cannot be negative")
+	p.check(server.WriteTimeout >= 0, "server.write_timeout", "cannot be negative")
+	for _, origin := range server.CORS.AllowedOrigins {
+		p.check(origin == "*" || isHTTPURL(origin), "server.cors.allowed_origins", "%q is not \"*\" or an http(s) origin", origin)
+	}
+	p.check(len(server.CORS.AllowedMethods) > 0, "server.cors.allowed_methods", "must not be empty")
+	p.check(server.Compression.MinSize >= 0, "server.compression.min_size", "cannot be negative")
+	if tls := server.TLS; tls.Enabled {
+		p.check(tls.CertFile != "", "server.tls.cert_file", "is required when TLS is enabled")
+		p.check(tls.KeyFile != "", "server.tls.key_file", "is required when TLS is enabled")
+		p.check(tls.MinVersion == "1.2" || tls.MinVersion == "1.3", "server.tls.min_version", "must be 1.2 or 1.3, got %q", tls.MinVersion)
+		p.check(tls.CipherPolicy == "default" || tls.CipherPolicy == "strict", "server.tls.cipher_policy", "must be default or strict, got %q", tls.CipherPolicy)
+		switch tls.ClientAuth {
+		case "none":
+		case "optional", "require":
+			p.check(tls.ClientCAFile != "", "server.tls.client_ca_file", "is required when client_auth is %s", tls.ClientAuth)
+		default:
+			p.add("server.tls.client_auth", "must be none, optional or require, got %q", tls.ClientAuth)
+		}
+		if tls.RedirectPort != "" {
+			redirect, err := strconv.Atoi(tls.RedirectPort)
+			p.check(err == nil && redirect > 0 && redirect <= 65535, "server.tls.redirect_port", "must be a port number between 1 and 65535, got %q", tls.RedirectPort)
+			p.check(tls.RedirectPort != server.Port, "server.tls.redirect_port", "must differ from server.port")
+		}
+	}
+
+	p.check(config.API.OpenWeatherMapApiKey != "", "api.openWeatherMapApiKey", "OpenWeatherMap API key is required")
+	p.check(isHTTPURL(config.API.BaseURL), "api.base_url", "must be an absolute http or https URL, got %q", config.API.BaseURL)
+	p.check(config.API.AlertsURL == "" || isHTTPURL(config.API.AlertsURL), "api.alerts_url", "must be empty or an absolute http or https URL, got %q", config.API.AlertsURL)
+	p.check(config.API.Timeout > 0, "api.timeout", "must be positive")
+	p.check(config.API.MaxRetries >= 0, "api.max_retries", "must not be negative")
+
+	if config.Auth.Enabled {
+		hasKeys := len(config.Auth.APIKeys) > 0 || config.Auth.KeysFile != ""
+		p.check(!hasKeys || config.Auth.HeaderName != "" || config.Auth.QueryParam != "",
+			"auth", "needs a header name or query parameter")
+		p.check(hasKeys || config.Auth.JWT.Enabled,
+			"auth", "needs api keys, a keys file or JWT verification when enabled")
+		for i, key := range config.Auth.APIKeys {
+			field := fmt.Sprintf("auth.api_keys[%d]", i)
+			p.check(key.ID != "", field+".id", "is required")
+			hash := strings.TrimPrefix(key.Hash, "sha256:")
+			p.check(len(hash) == 64 && isHex(hash), field+".hash", "must be a hex SHA-256 hash, optionally prefixed with sha256:")
+		}
+	}
+	if jwt := config.Auth.JWT; config.Auth.Enabled && jwt.Enabled {
+		p.check(jwt.HMACSecret != "" || jwt.PublicKeyFile != "" || jwt.JWKSFile != "",
+			"auth.jwt", "needs an hmac secret, public key file or jwks file")
+		p.check(len(jwt.Algorithms) > 0, "auth.jwt.algorithms", "needs at least one allowed algorithm")
+		for _, alg := range jwt.Algorithms {
+			p.check(jwtAlgorithms[alg], "auth.jwt.algorithms", "unsupported algorithm %q (use HS256, RS256 or ES256)", alg)
+			if alg == "HS256" {
+				p.check(jwt.HMACSecret != "", "auth.jwt.hmac_secret", "is required when HS256 is allowed")
+			}
+		}
+		p.check(jwt.Leeway >= 0, "auth.jwt.leeway", "cannot be negative")
+	}
+
+	if rl := config.RateLimit; rl.Enabled {
+		for _, name := range []string{rl.DefaultTier, rl.AnonymousTier} {
+			_, ok := rl.Tiers[name]
+			p.check(ok, "rate_limit.tiers", "tier %q is not defined", name)
+		}
+		for name, tier := range rl.Tiers {
+			p.check(tier.RequestsPerMinute > 0 && tier.Burst > 0,
+				"rate_limit.tiers."+name, "needs positive requests per minute and burst")
+		}
+		p.check(rl.MaxEntries > 0, "rate_limit.max_entries", "must be positive")
+		p.check(rl.IdleTimeout > 0, "rate_limit.idle_timeout", "must be positive")
+	}
+	for _, proxy := range config.RateLimit.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		p.check(cidrErr == nil || net.ParseIP(proxy) != nil, "rate_limit.trusted_proxies", "%q is not an IP address or CIDR range", proxy)
+	}
+
+	if config.Quota.Enabled {
+		p.check(config.Quota.PerMinute > 0, "quota.per_minute", "must be positive")
+		p.check(config.Quota.PerDay > 0, "quota.per_day", "must be positive")
+		p.check(config.Quota.BackgroundReserve >= 0 && config.Quota.BackgroundReserve <= 100,
+			"quota.background_reserve", "must be a percentage between 0 and 100")
+	}
+
+	if config.Breaker.Enabled {
+		p.check(config.Breaker.FailureThreshold > 0, "breaker.failure_threshold", "must be positive")
+		p.check(config.Breaker.Cooldown > 0, "breaker.cooldown", "must be positive")
+	}
+
+	if config.Cache.Enabled {
+		p.check(config.Cache.WeatherTTL > 0, "cache.weather_ttl", "must be positive")
+		p.check(config.Cache.ForecastTTL > 0, "cache.forecast_ttl", "must be positive")
+		p.check(config.Cache.MaxStale >= 0, "cache.max_stale", "cannot be negative")
+		switch config.Cache.Backend {
+		case "memory":
+		case "redis":
+			_, _, err := net.SplitHostPort(config.Cache.Redis.Address)
+			p.check(err == nil, "cache.redis.address", "must be host:port, got %q", config.Cache.Redis.Address)
+			p.check(config.Cache.Redis.DB >= 0, "cache.redis.db", "cannot be negative")
+		default:
+			p.add("cache.backend", "must be memory or redis, got %q", config.Cache.Backend)
+		}
+	}
+
+	p.check(config.Stream.MaxConnections > 0, "stream.max_connections", "must be positive")
+	p.check(config.Stream.MaxCities > 0, "stream.max_cities", "must be positive")
+	p.check(config.Stream.RefreshInterval > 0, "stream.refresh_interval", "must be positive")
+	p.check(config.Stream.ForecastRefreshInterval > 0, "stream.forecast_refresh_interval", "must be positive")
+	p.check(config.Stream.HeartbeatInterval > 0, "stream.heartbeat_interval", "must be positive")
+
+	if config.Storage.Enabled {
+		p.check(config.Storage.Driver != "", "storage.driver", "is required when storage is enabled")
+		p.check(config.Storage.Path != "", "storage.path", "is required when storage is enabled")
+		p.check(config.Storage.RetentionDays >= 0, "storage.retention_days", "cannot be negative")
+	}
+
+	p.check(config.History.MaxRangeDays > 0, "history.max_range_days", "must be positive")
+	p.check(config.History.DefaultPageSize > 0, "history.default_page_size", "must be positive")
+	p.check(config.History.MaxPageSize >= config.History.DefaultPageSize,
+		"history.max_page_size", "must be at least the default page size")
+
+	if config.Collector.Enabled {
+		p.check(config.Collector.Schedule != "", "collector.schedule", "is required when the collector is enabled")
+		p.check(config.Collector.RequestsPerMinute > 0, "collector.requests_per_minute", "must be positive")
+		p.check(len(config.Collector.Cities) > 0 || config.Collector.CitiesFile != "",
+			"collector", "needs cities or a cities file when enabled")
+	}
+
+	switch strings.ToLower(config.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		p.add("log.level", "must be debug, info, warn or error, got %q", config.Log.Level)
+	}
+	switch strings.ToLower(config.Log.Format) {
+	case "json", "text":
+	default:
+		p.add("log.format", "must be json or text, got %q", config.Log.Format)
+	}
+
+	if config.Tracing.Enabled {
+		switch strings.ToLower(config.Tracing.Exporter) {
+		case "otlp", "stdout":
+		default:
+			p.add("tracing.exporter", "must be otlp or stdout, got %q", config.Tracing.Exporter)
+		}
+		p.check(config.Tracing.SampleRatio >= 0 && config.Tracing.SampleRatio <= 1,
+			"tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if config.Admin.Enabled {
+		host, port, err := net.SplitHostPort(config.Admin.Address)
+		if err != nil || port == "" {
+			p.add("admin.address", "must be host:port, got %q", config.Admin.Address)
+		} else {
+			p.check(config.Admin.Token != "" || isLoopback(host),
+				"admin.token", "is required when the admin listener is not bound to a loopback address")
+			p.check(port != config.Server.Port, "admin.address", "must use a different port than server.port")
+		}
+	}
+
+	p.check(config.Health.CheckTimeout > 0, "health.check_timeout", "must be positive")
+	if config.Health.UpstreamProbe {
+		p.check(config.Health.ProbeCity != "", "health.probe_city", "is required when the upstream probe is enabled")
+		p.check(config.Health.ProbeInterval > 0, "health.probe_interval", "must be positive")
+	}
+
+	checksMu.RLock()
+	defer checksMu.RUnlock()
+	for _, c := range checks {
+		if err := c.check(config); err != nil {
+			p.add(c.field, "%v", err)
+		}
+	}
+}
+
+// Warnings describes valid but insecure combinations of settings
+func (c *Config) Warnings() []string {
+	var warnings []string
+
+	cors := c.Server.CORS
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" && cors.AllowCredentials {
+			warnings = append(warnings, "server.cors: allow_credentials with a wildcard origin lets any site make credentialed requests")
+			break
+		}
+	}
+	if u, err := url.Parse(c.API.BaseURL); err == nil && u.Scheme == "http" {
+		warnings = append(warnings, "api.base_url: plain http sends the OpenWeatherMap API key unencrypted")
+	}
+	if u, err := url.Parse(c.API.AlertsURL); err == nil && u.Scheme == "http" {
+		warnings = append(warnings, "api.alerts_url: plain http sends the OpenWeatherMap API key unencrypted")
+	}
+	if jwt := c.Auth.JWT; jwt.Enabled && jwt.HMACSecret != "" && len(jwt.HMACSecret) < 32 {
+		warnings = append(warnings, "auth.jwt.hmac_secret: shorter than 32 bytes")
+	}
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if proxy == "0.0.0.0/0" || proxy == "::/0" {
+			warnings = append(warnings, fmt.Sprintf("rate_limit.trusted_proxies: %s lets any client choose its own address", proxy))
+		}
+	}
+	return warnings
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL with a host
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isLoopback reports whether host is localhost or a loopback IP address
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
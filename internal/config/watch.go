@@ -0,0 +1,104 @@
+package config
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce collapses the bursts of events editors produce when saving
+const watchDebounce = 250 * time.Millisecond
+
+// Watcher reports changes to config files. It watches the containing
+// directories so files that editors replace by renaming are still noticed.
+type Watcher struct {
+	paths   map[string]bool
+	watcher *fsnotify.Watcher
+	changes chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWatcher starts watching paths for changes, reporting a change to any of them
+func NewWatcher(paths ...string) (*Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			fw.Close()
+			return nil, err
+		}
+		if err := fw.Add(filepath.Dir(abs)); err != nil {
+			fw.Close()
+			return nil, err
+		}
+		watched[abs] = true
+	}
+
+	w := &Watcher{
+		paths:   watched,
+		watcher: fw,
+		changes: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.loop()
+	return w, nil
+}
+
+// Changes receives a value after a file has been written, created or replaced
+func (w *Watcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	close(w.done)
+	err := w.watcher.Close()
+	w.wg.Wait()
+	return err
+}
+
+func (w *Watcher) loop() {
+	defer w.wg.Done()
+
+	timer := time.NewTimer(watchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !w.paths[filepath.Clean(event.Name)] {
+				continue
+			}
+			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename) {
+				timer.Reset(watchDebounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			slog.Warn("file watcher error", "error", err)
+		case <-timer.C:
+			// Coalesce with a pending notification the server has not handled yet
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// expectChange fails t unless w reports a change within a second
+func expectChange(t *testing.T, w *Watcher) {
+	t.Helper()
+
+	select {
+	case <-w.Changes():
+	case <-time.After(time.Second):
+		t.Fatal("no change reported")
+	}
+}
+
+// expectNoChange fails t if w reports a change within a few debounce periods
+func expectNoChange(t *testing.T, w *Watcher) {
+	t.Helper()
+
+	select {
+	case <-w.Changes():
+		t.Fatal("unexpected change reported")
+	case <-time.After(4 * watchDebounce):
+	}
+}
+
+func TestWatcherDebouncesWrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	w, err := NewWatcher(path)
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer w.Close()
+
+	// A burst of writes is reported once
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(path, []byte(`{"log": {}}`), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		time.Sleep(watchDebounce / 10)
+	}
+	expectChange(t, w)
+	expectNoChange(t, w)
+
+	// Other files in the directory are ignored
+	if err := os.WriteFile(filepath.Join(dir, "other.json"), []byte(`{}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	expectNoChange(t, w)
+}
+
+func TestWatcherNoticesReplacedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte("log: {}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	w, err := NewWatcher(path)
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer w.Close()
+
+	// Editors often save by writing a temporary file and renaming it over the original
+	tmp := filepath.Join(dir, ".config.yaml.swp")
+	if err := os.WriteFile(tmp, []byte("log: {level: debug}\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	expectChange(t, w)
+}
+
+func TestWatcherClose(t *testing.T) {
+	w, err := NewWatcher(filepath.Join(t.TempDir(), "config.json"))
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- w.Close() }()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Close did not return")
+	}
+}
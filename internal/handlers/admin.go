@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// AdminHandler handles operational endpoints
+type AdminHandler struct {
+	weatherService *services.WeatherService
+	config         atomic.Pointer[config.Config]
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(weatherService *services.WeatherService, cfg *config.Config) *AdminHandler {
+	h := &AdminHandler{
+		weatherService: weatherService,
+	}
+	h.config.Store(cfg)
+	return h
+}
+
+// SetConfig replaces the configuration reported by GetConfig after a reload
+func (h *AdminHandler) SetConfig(cfg *config.Config) {
+	h.config.Store(cfg)
+}
+
+// GetQuota handles GET /api/v1/admin/quota
+func (h *AdminHandler) GetQuota(w http.ResponseWriter, r *http.Request) {
+	status, ok := h.weatherService.BudgetStatus()
+	if !ok {
+		utils.WriteErrorResponse(w, r, "Upstream quota tracking is not enabled", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, status)
+}
+
+// GetBreaker handles GET /admin/breaker, reporting the upstream circuit breaker state
+func (h *AdminHandler) GetBreaker(w http.ResponseWriter, r *http.Request) {
+	status, ok := h.weatherService.BreakerStatus()
+	if !ok {
+		utils.WriteErrorResponse(w, r, "Circuit breaker is not enabled", http.StatusNotFound)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, status)
+}
+
+// ControlBreaker handles POST /admin/breaker/{action}. open and close pin the
+// circuit breaker in that state until reset returns it to automatic operation.
+func (h *AdminHandler) ControlBreaker(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+
+	var status breaker.Status
+	var err error
+	switch action {
+	case "open":
+		status, err = h.weatherService.ForceBreaker(breaker.StateOpen)
+	case "close":
+		status, err = h.weatherService.ForceBreaker(breaker.StateClosed)
+	case "reset":
+		status, err = h.weatherService.ResetBreaker()
+	default:
+		utils.WriteErrorResponse(w, r, "Action must be open, close or reset", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrBreakerDisabled) {
+		utils.WriteErrorResponse(w, r, "Circuit breaker is not enabled", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	audit(r, "breaker."+action, "state", status.State)
+	utils.WriteSuccessResponse(w, r, status)
+}
+
+// GetConfig handles GET /api/v1/admin/config, returning the effective configuration with secrets masked
+func (h *AdminHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	redacted, err := h.config.Load().Redacted()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to redact configuration", "error", err)
+		utils.WriteErrorResponse(w, r, "Failed to render configuration", http.StatusInternalServerError)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, redacted)
+}
+
+// maxPrewarmCities bounds the upstream calls a single pre-warm request can make
+const maxPrewarmCities = 100
+
+// GetCache handles GET /admin/cache, returning cache size and memory estimates
+func (h *AdminHandler) GetCache(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCache(w, r) {
+		return
+	}
+	stats, err := h.weatherService.CacheStats(r.Context())
+	if err != nil {
+		h.cacheFailed(w, r, err)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, stats)
+}
+
+// GetCacheEntries handles GET /admin/cache/entries, listing cached responses
+// with their age and remaining TTL, optionally filtered by a ?pattern= glob
+func (h *AdminHandler) GetCacheEntries(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCache(w, r) {
+		return
+	}
+	entries, err := h.weatherService.CacheEntries(r.Context(), r.URL.Query().Get("pattern"))
+	if errors.Is(err, services.ErrInvalidPattern) {
+		utils.WriteErrorResponse(w, r, "Invalid pattern", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		h.cacheFailed(w, r, err)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, models.CacheEntriesResponse{Entries: entries, Count: len(entries)})
+}
+
+// PurgeCache handles DELETE /admin/cache. ?city= removes one city's responses
+// and ?pattern= those whose key matches a glob; without either every cached
+// response is removed.
+func (h *AdminHandler) PurgeCache(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCache(w, r) {
+		return
+	}
+	city := r.URL.Query().Get("city")
+	pattern := r.URL.Query().Get("pattern")
+
+	var purged int
+	var err error
+	action := "cache.purge"
+	var attrs []interface{}
+	switch {
+	case city != "" && pattern != "":
+		utils.WriteErrorResponse(w, r, "Use either city or pattern, not both", http.StatusBadRequest)
+		return
+	case city != "":
+		if err := validateCityName(city); err != nil {
+			utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		purged, err = h.weatherService.PurgeCacheLocation(r.Context(), models.CityLocation(city))
+		attrs = []interface{}{"city", city}
+	case pattern != "":
+		purged, err = h.weatherService.PurgeCacheMatching(r.Context(), pattern)
+		if errors.Is(err, services.ErrInvalidPattern) {
+			utils.WriteErrorResponse(w, r, "Invalid pattern", http.StatusBadRequest)
+			return
+		}
+		attrs = []interface{}{"pattern", pattern}
+	default:
+		purged, err = h.weatherService.PurgeCache(r.Context())
+		action = "cache.purge_all"
+	}
+	// Failed purges are audited too, since some entries may already be gone
+	if err != nil {
+		audit(r, action, append(attrs, "error", err.Error())...)
+		h.cacheFailed(w, r, err)
+		return
+	}
+	audit(r, action, append(attrs, "purged", purged)...)
+	utils.WriteSuccessResponse(w, r, models.CachePurgeResponse{Purged: purged})
+}
+
+// PrewarmCache handles POST /admin/cache/prewarm, fetching current weather
+// and forecasts for the listed cities into the cache
+func (h *AdminHandler) PrewarmCache(w http.ResponseWriter, r *http.Request) {
+	if !h.requireCache(w, r) {
+		return
+	}
+	var req models.CachePrewarmRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, r, "Request body must be JSON with a cities array", http.StatusBadRequest)
+		return
+	}
+	if len(req.Cities) == 0 || len(req.Cities) > maxPrewarmCities {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("Between 1 and %d cities are required", maxPrewarmCities), http.StatusBadRequest)
+		return
+	}
+	for _, city := range req.Cities {
+		if err := validateCityName(city); err != nil {
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("Invalid city %q: %v", city, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	audit(r, "cache.prewarm", "cities", req.Cities)
+	utils.WriteSuccessResponse(w, r, h.weatherService.PrewarmCache(r.Context(), req.Cities))
+}
+
+// requireCache writes a 404 and returns false when caching is disabled
+func (h *AdminHandler) requireCache(w http.ResponseWriter, r *http.Request) bool {
+	if !h.weatherService.CachingEnabled() {
+		utils.WriteErrorResponse(w, r, "Response caching is not enabled", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// cacheFailed reports a cache backend error, such as an unreachable Redis server
+func (h *AdminHandler) cacheFailed(w http.ResponseWriter, r *http.Request, err error) {
+	slog.ErrorContext(r.Context(), "cache backend request failed", "error", err)
+	utils.WriteErrorResponse(w, r, "Cache backend unavailable", http.StatusServiceUnavailable)
+}
+
+// audit logs an administrative action with the identity of the caller
+func audit(r *http.Request, action string, attrs ...interface{}) {
+	caller := "anonymous"
+	if consumer, ok := auth.ConsumerFromContext(r.Context()); ok {
+		caller = consumer.ID
+	}
+	attrs = append([]interface{}{"action", action, "caller", caller, "remote_addr", r.RemoteAddr}, attrs...)
+	slog.InfoContext(r.Context(), "admin action", attrs...)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/cache"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// newTestAdminHandler returns an admin handler whose weather service calls
+// upstream, caches in memory and is guarded by b when it is not nil
+func newTestAdminHandler(t *testing.T, upstream http.HandlerFunc, b *breaker.Breaker) *AdminHandler {
+	t.Helper()
+
+	srv := httptest.NewServer(upstream)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.Config{
+		API:   config.APIConfig{BaseURL: srv.URL, Timeout: 5},
+		Cache: config.CacheConfig{Enabled: true, WeatherTTL: 300, ForecastTTL: 1800},
+	}
+	return NewAdminHandler(services.NewWeatherService(cfg, cache.NewMemory(time.Hour), nil, nil, b), cfg)
+}
+
+func TestControlBreaker(t *testing.T) {
+	b := breaker.New(5, time.Minute)
+	h := newTestAdminHandler(t, func(w http.ResponseWriter, r *http.Request) {}, b)
+
+	tests := []struct {
+		action     string
+		wantStatus int
+		wantState  string
+		wantForced bool
+	}{
+		{"open", http.StatusOK, breaker.StateOpen, true},
+		{"close", http.StatusOK, breaker.StateClosed, true},
+		{"reset", http.StatusOK, breaker.StateClosed, false},
+		{"half_open", http.StatusBadRequest, breaker.StateClosed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/admin/breaker/"+tt.action, nil), map[string]string{"action": tt.action})
+			rec := httptest.NewRecorder()
+			h.ControlBreaker(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			rec = httptest.NewRecorder()
+			h.GetBreaker(rec, httptest.NewRequest(http.MethodGet, "/admin/breaker", nil))
+			var status breaker.Status
+			if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if status.State != tt.wantState || status.Forced != tt.wantForced {
+				t.Errorf("breaker = %+v, want %s with forced %v", status, tt.wantState, tt.wantForced)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		h := newTestAdminHandler(t, func(w http.ResponseWriter, r *http.Request) {}, nil)
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/admin/breaker/open", nil), map[string]string{"action": "open"})
+		rec := httptest.NewRecorder()
+		h.ControlBreaker(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("control without a breaker = %d, want 404", rec.Code)
+		}
+		rec = httptest.NewRecorder()
+		h.GetBreaker(rec, httptest.NewRequest(http.MethodGet, "/admin/breaker", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status without a breaker = %d, want 404", rec.Code)
+		}
+	})
+}
+
+// fakeWeatherUpstream answers every current weather and forecast request
+func fakeWeatherUpstream(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"name": r.URL.Query().Get("q")})
+}
+
+// prewarm fills the cache of h with the weather and forecast of cities
+func prewarm(t *testing.T, h *AdminHandler, cities ...string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(models.CachePrewarmRequest{Cities: cities})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	h.PrewarmCache(rec, httptest.NewRequest(http.MethodPost, "/admin/cache/prewarm", bytes.NewReader(body)))
+	return rec
+}
+
+func TestGetCacheEntriesPattern(t *testing.T) {
+	h := newTestAdminHandler(t, fakeWeatherUpstream, nil)
+	if rec := prewarm(t, h, "London", "Paris", "Lisbon"); rec.Code != http.StatusOK {
+		t.Fatalf("prewarm = %d: %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		name       string
+		pattern    string
+		wantStatus int
+		wantCount  int
+	}{
+		{"all entries", "", http.StatusOK, 6},
+		{"one kind", "forecast|*", http.StatusOK, 3},
+		{"one city", "*|city:london", http.StatusOK, 2},
+		{"prefix", "weather|city:l*", http.StatusOK, 2},
+		{"no match", "alerts|*", http.StatusOK, 0},
+		{"invalid glob", "weather|[", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/cache/entries?pattern="+url.QueryEscape(tt.pattern), nil)
+			rec := httptest.NewRecorder()
+			h.GetCacheEntries(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp models.CacheEntriesResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Count != tt.wantCount || len(resp.Entries) != tt.wantCount {
+				t.Errorf("count = %d with %d entries, want %d", resp.Count, len(resp.Entries), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestPurgeCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantPurged int
+	}{
+		{"everything", "", http.StatusOK, 4},
+		{"one city", "?city=London", http.StatusOK, 2},
+		{"pattern", "?pattern=" + url.QueryEscape("forecast|*"), http.StatusOK, 2},
+		{"city and pattern", "?city=London&pattern=" + url.QueryEscape("forecast|*"), http.StatusBadRequest, 0},
+		{"invalid glob", "?pattern=" + url.QueryEscape("[a-"), http.StatusBadRequest, 0},
+		{"invalid city", "?city=" + url.QueryEscape("<script>"), http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestAdminHandler(t, fakeWeatherUpstream, nil)
+			if rec := prewarm(t, h, "London", "Paris"); rec.Code != http.StatusOK {
+				t.Fatalf("prewarm = %d: %s", rec.Code, rec.Body)
+			}
+
+			rec := httptest.NewRecorder()
+			h.PurgeCache(rec, httptest.NewRequest(http.MethodDelete, "/admin/cache"+tt.query, nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			var remaining models.CacheEntriesResponse
+			entries := httptest.NewRecorder()
+			h.GetCacheEntries(entries, httptest.NewRequest(http.MethodGet, "/admin/cache/entries", nil))
+			if err := json.Unmarshal(entries.Body.Bytes(), &remaining); err != nil {
+				t.Fatalf("decode entries: %v", err)
+			}
+			if remaining.Count != 4-tt.wantPurged {
+				t.Errorf("%d entries left, want %d", remaining.Count, 4-tt.wantPurged)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp models.CachePurgeResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Purged != tt.wantPurged {
+				t.Errorf("purged = %d, want %d", resp.Purged, tt.wantPurged)
+			}
+		})
+	}
+}
+
+// failingCache is a memory cache whose purges fail
+type failingCache struct {
+	*cache.Memory
+}
+
+func (failingCache) Clear(ctx context.Context) (int, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestPurgeCacheAuditsFailure(t *testing.T) {
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	cfg := &config.Config{Cache: config.CacheConfig{Enabled: true}}
+	h := NewAdminHandler(services.NewWeatherService(cfg, failingCache{cache.NewMemory(time.Hour)}, nil, nil, nil), cfg)
+
+	rec := httptest.NewRecorder()
+	h.PurgeCache(rec, httptest.NewRequest(http.MethodDelete, "/admin/cache", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+
+	var audited bool
+	dec := json.NewDecoder(&logs)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		if entry["msg"] != "admin action" {
+			continue
+		}
+		audited = true
+		if entry["action"] != "cache.purge_all" || entry["error"] != "connection refused" {
+			t.Errorf("audit record = %v, want the purge with its error", entry)
+		}
+		if _, ok := entry["purged"]; ok {
+			t.Errorf("audit record = %v reports purged entries for a failed purge", entry)
+		}
+	}
+	if !audited {
+		t.Error("failed purge was not audited")
+	}
+}
+
+func TestPrewarmCacheLimits(t *testing.T) {
+	var calls atomic.Int64
+	h := newTestAdminHandler(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fakeWeatherUpstream(w, r)
+	}, nil)
+
+	tooMany := make([]string, maxPrewarmCities+1)
+	for i := range tooMany {
+		tooMany[i] = "City" + strconv.Itoa(i)
+	}
+
+	tests := []struct {
+		name       string
+		cities     []string
+		wantStatus int
+	}{
+		{"no cities", nil, http.StatusBadRequest},
+		{"over the limit", tooMany, http.StatusBadRequest},
+		{"invalid city", []string{"London", "<script>"}, http.StatusBadRequest},
+		{"at the limit", tooMany[:maxPrewarmCities], http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := calls.Load()
+			rec := prewarm(t, h, tt.cities...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			wantCalls := int64(0)
+			if tt.wantStatus == http.StatusOK {
+				wantCalls = int64(2 * len(tt.cities))
+			}
+			if got := calls.Load() - before; got != wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, wantCalls)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.PrewarmCache(rec, httptest.NewRequest(http.MethodPost, "/admin/cache/prewarm", strings.NewReader("London")))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("non-JSON body = %d, want 400", rec.Code)
+	}
+}
@@ -22,5 +22,20 @@ func NewHealthHandler(healthService *services.HealthService) *HealthHandler {
 // GetHealth handles GET /api/v1/health
 func (h *HealthHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
 	health := h.healthService.GetHealthStatus()
-	utils.WriteSuccessResponse(w, health)
+	utils.WriteSuccessResponse(w, r, health)
+}
+
+// GetLiveness handles GET /livez
+func (h *HealthHandler) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, r, h.healthService.Live())
+}
+
+// GetReadiness handles GET /readyz, answering 503 when any check is down
+func (h *HealthHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	readiness := h.healthService.Ready(r.Context())
+	if readiness.Status != services.StatusReady {
+		utils.WriteJSONResponse(w, http.StatusServiceUnavailable, readiness)
+		return
+	}
+	utils.WriteSuccessResponse(w, r, readiness)
 }
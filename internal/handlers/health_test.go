@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/storage"
+)
+
+func TestGetReadiness(t *testing.T) {
+	tests := []struct {
+		name           string
+		upstreamStatus int
+		closeStore     bool
+		forceOpen      bool
+		status         int
+		down           []string
+	}{
+		{"ready", http.StatusOK, false, false, http.StatusOK, nil},
+		{"city not found still reaches upstream", http.StatusNotFound, false, false, http.StatusOK, nil},
+		{"storage down", http.StatusOK, true, false, http.StatusServiceUnavailable, []string{"storage"}},
+		{"upstream probe failing", http.StatusBadGateway, false, false, http.StatusServiceUnavailable, []string{"upstream"}},
+		{"circuit breaker open", http.StatusOK, false, true, http.StatusServiceUnavailable, []string{"circuit_breaker", "upstream"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.upstreamStatus)
+				w.Write([]byte(`{"name":"London"}`))
+			}))
+			defer upstream.Close()
+
+			store, err := storage.OpenBoltStore(filepath.Join(t.TempDir(), "history.db"))
+			if err != nil {
+				t.Fatalf("open store: %v", err)
+			}
+			if tt.closeStore {
+				store.Close()
+			} else {
+				defer store.Close()
+			}
+
+			b := breaker.New(5, time.Minute)
+			if tt.forceOpen {
+				b.Force(breaker.StateOpen)
+			}
+			cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}
+			ws := services.NewWeatherService(cfg, nil, nil, nil, b)
+
+			hs := services.NewHealthService(time.Second)
+			hs.RegisterCheck("upstream", func(ctx context.Context) error {
+				return ws.ProbeUpstream(ctx, "London")
+			}, time.Minute)
+			hs.RegisterCheck("storage", store.Ping, 0)
+			hs.RegisterCheck("circuit_breaker", func(ctx context.Context) error {
+				return b.Ready()
+			}, 0)
+
+			rec := httptest.NewRecorder()
+			NewHealthHandler(hs).GetReadiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			var readiness models.ReadinessResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &readiness); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			var down []string
+			for name, check := range readiness.Checks {
+				if check.Status == services.CheckDown {
+					if check.Error == "" {
+						t.Errorf("check %s is down without an error", name)
+					}
+					down = append(down, name)
+				}
+			}
+			sort.Strings(down)
+			if len(readiness.Checks) != 3 || len(down) != len(tt.down) {
+				t.Fatalf("checks = %+v, want %v down", readiness.Checks, tt.down)
+			}
+			for i := range down {
+				if down[i] != tt.down[i] {
+					t.Errorf("down checks = %v, want %v", down, tt.down)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// HistoryHandler handles historical weather requests
+type HistoryHandler struct {
+	historyService *services.HistoryService
+	config         config.HistoryConfig
+}
+
+// NewHistoryHandler creates a new history handler
+func NewHistoryHandler(historyService *services.HistoryService, cfg config.HistoryConfig) *HistoryHandler {
+	return &HistoryHandler{
+		historyService: historyService,
+		config:         cfg,
+	}
+}
+
+// GetHistory handles GET /api/v1/history/{city}?from=&to=&interval=hour|day|month&page=&page_size=
+func (h *HistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	city := mux.Vars(r)["city"]
+	if err := validateCityName(city); err != nil {
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.historyService.Enabled() {
+		utils.WriteErrorResponse(w, r, "History storage is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = services.IntervalHour
+	}
+	if !services.ValidInterval(interval) {
+		utils.WriteErrorResponse(w, r, "interval must be one of hour, day or month", http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now().UTC()
+	if raw := query.Get("to"); raw != "" {
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("invalid to: %v", err), http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+	from := to.Add(-24 * time.Hour)
+	if raw := query.Get("from"); raw != "" {
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("invalid from: %v", err), http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	if !from.Before(to) {
+		utils.WriteErrorResponse(w, r, "from must be before to", http.StatusBadRequest)
+		return
+	}
+	maxRange := time.Duration(h.config.MaxRangeDays) * 24 * time.Hour
+	if to.Sub(from) > maxRange {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("requested range exceeds the maximum of %d days", h.config.MaxRangeDays), http.StatusBadRequest)
+		return
+	}
+
+	page, err := parsePositiveInt(query.Get("page"), 1)
+	if err != nil {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("invalid page: %v", err), http.StatusBadRequest)
+		return
+	}
+	pageSize, err := parsePositiveInt(query.Get("page_size"), h.config.DefaultPageSize)
+	if err != nil {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("invalid page_size: %v", err), http.StatusBadRequest)
+		return
+	}
+	if pageSize > h.config.MaxPageSize {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("page_size cannot exceed %d", h.config.MaxPageSize), http.StatusBadRequest)
+		return
+	}
+
+	loc := models.CityLocation(city)
+	buckets, err := h.historyService.GetHistory(r.Context(), loc, from, to, interval)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "history query failed", "location", loc.String(), "error", err)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Pages past the end are empty. Compare page numbers rather than
+	// offsets, which overflow for huge pages.
+	total := len(buckets)
+	totalPages := (total + pageSize - 1) / pageSize
+	start := total
+	if page <= totalPages {
+		start = (page - 1) * pageSize
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+
+	// History is not cached server-side, so clients revalidate with the
+	// validators; it changes only when a newer observation is recorded
+	var lastObserved time.Time
+	for _, b := range buckets {
+		if b.LastObservedAt.After(lastObserved) {
+			lastObserved = b.LastObservedAt
+		}
+	}
+	utils.SetCacheHeaders(w, lastObserved, 0)
+	utils.WriteSuccessResponse(w, r, models.HistoryResponse{
+		Location: loc.City,
+		From:     from,
+		To:       to,
+		Interval: interval,
+		Buckets:  append([]models.HistoryBucket{}, buckets[start:end]...),
+		Pagination: models.Pagination{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: total,
+			TotalPages: totalPages,
+		},
+	})
+}
+
+// parseTimeParam accepts RFC 3339 timestamps, YYYY-MM-DD dates or Unix seconds
+func parseTimeParam(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t.UTC(), nil
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC 3339 time, YYYY-MM-DD date or Unix seconds")
+}
+
+// parsePositiveInt parses an optional positive integer query parameter
+func parsePositiveInt(raw string, fallback int) (int, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	val, err := strconv.Atoi(raw)
+	if err != nil || val < 1 {
+		return 0, fmt.Errorf("must be a positive integer")
+	}
+	return val, nil
+}
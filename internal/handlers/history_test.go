@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/storage"
+	"github.com/gorilla/mux"
+)
+
+// newTestHistoryHandler returns a handler over a bolt store holding hours
+// hourly observations for London, the last one an hour ago
+func newTestHistoryHandler(t *testing.T, hours int) *HistoryHandler {
+	t.Helper()
+
+	store, err := storage.OpenBoltStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	loc := models.CityLocation("London")
+	now := time.Now().UTC().Truncate(time.Hour)
+	for i := 1; i <= hours; i++ {
+		obs := models.Observation{
+			Location:   loc.Key(),
+			Name:       "London",
+			ObservedAt: now.Add(-time.Duration(i) * time.Hour),
+			RecordedAt: now,
+			Temp:       float64(i),
+		}
+		if err := store.Record(context.Background(), obs); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	return NewHistoryHandler(services.NewHistoryService(store), config.HistoryConfig{
+		MaxRangeDays:    31,
+		DefaultPageSize: 2,
+		MaxPageSize:     100,
+	})
+}
+
+func TestGetHistoryPagination(t *testing.T) {
+	h := newTestHistoryHandler(t, 5)
+
+	tests := []struct {
+		name        string
+		page        string
+		wantBuckets int
+	}{
+		{"first page", "1", 2},
+		{"last partial page", "3", 1},
+		{"past the end", "4", 0},
+		{"largest int", strconv.Itoa(int(^uint(0) >> 1)), 0},
+		{"overflowing offset", strconv.Itoa(int(^uint(0)>>1)/2 + 2), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/history/London?page="+tt.page, nil)
+			req = mux.SetURLVars(req, map[string]string{"city": "London"})
+			rec := httptest.NewRecorder()
+
+			h.GetHistory(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+			}
+			var resp models.HistoryResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(resp.Buckets) != tt.wantBuckets {
+				t.Errorf("got %d buckets, want %d", len(resp.Buckets), tt.wantBuckets)
+			}
+			if resp.Pagination.TotalItems != 5 || resp.Pagination.TotalPages != 3 {
+				t.Errorf("pagination = %+v, want 5 items on 3 pages", resp.Pagination)
+			}
+		})
+	}
+}
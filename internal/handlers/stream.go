@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/utils"
+)
+
+// StreamHandler handles Server-Sent Events streams of live weather updates
+type StreamHandler struct {
+	refresher      *services.Refresher
+	maxConnections int
+	maxCities      int
+	heartbeat      time.Duration
+	active         atomic.Int64
+}
+
+// NewStreamHandler creates a new stream handler
+func NewStreamHandler(refresher *services.Refresher, maxConnections, maxCities int, heartbeat time.Duration) *StreamHandler {
+	return &StreamHandler{
+		refresher:      refresher,
+		maxConnections: maxConnections,
+		maxCities:      maxCities,
+		heartbeat:      heartbeat,
+	}
+}
+
+// StreamWeather handles GET /api/v1/stream/weather?cities=a,b,c
+func (h *StreamHandler) StreamWeather(w http.ResponseWriter, r *http.Request) {
+	cities := parseCityList(r.URL.Query().Get("cities"))
+	if len(cities) == 0 {
+		utils.WriteErrorResponse(w, r, "cities parameter is required", http.StatusBadRequest)
+		return
+	}
+	if h.maxCities > 0 && len(cities) > h.maxCities {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("at most %d cities can be streamed at once", h.maxCities), http.StatusBadRequest)
+		return
+	}
+	for _, city := range cities {
+		if err := validateCityName(city); err != nil {
+			utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if n := h.active.Add(1); h.maxConnections > 0 && n > int64(h.maxConnections) {
+		h.active.Add(-1)
+		w.Header().Set("Retry-After", strconv.Itoa(int(h.heartbeat.Seconds())))
+		utils.WriteErrorResponse(w, r, "too many active streams, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.active.Add(-1)
+
+	// Streams outlive the server write timeout, so lift the deadline for this response
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		slog.WarnContext(r.Context(), "stream: failed to clear write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	topics := make([]services.Topic, 0, len(cities))
+	for _, city := range cities {
+		topics = append(topics, services.WeatherTopic(models.CityLocation(city)))
+	}
+	sub := h.refresher.Subscribe(topics)
+	defer sub.Close()
+
+	// Replay missed updates when the client can resume from its last ID,
+	// otherwise send the latest state. The snapshot is ordered by ID, so the
+	// lastID filter below never drops a city.
+	var lastID uint64
+	var backlog []services.Update
+	resumed := false
+	if id, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		if backlog, resumed = h.refresher.Since(id, topics); resumed {
+			lastID = id
+		}
+	}
+	if !resumed {
+		backlog = sub.Snapshot()
+	}
+
+	fmt.Fprintf(w, "retry: %d\n\n", h.heartbeat.Milliseconds())
+	for _, update := range backlog {
+		if update.ID <= lastID {
+			continue
+		}
+		if err := writeUpdateEvent(w, update); err != nil {
+			return
+		}
+		lastID = update.ID
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.refresher.Done():
+			return
+		case update := <-sub.Updates():
+			if update.ID <= lastID {
+				continue
+			}
+			if err := writeUpdateEvent(w, update); err != nil {
+				return
+			}
+			lastID = update.ID
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeUpdateEvent writes a single weather update as an SSE event
+func writeUpdateEvent(w http.ResponseWriter, update services.Update) error {
+	payload, err := json.Marshal(update.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", update.ID, update.Topic.Kind, payload)
+	return err
+}
+
+// parseCityList splits a comma separated city list, dropping blanks and duplicates
+func parseCityList(raw string) []string {
+	var cities []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, ",") {
+		city := strings.TrimSpace(part)
+		key := strings.ToLower(city)
+		if city == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cities = append(cities, city)
+	}
+	return cities
+}
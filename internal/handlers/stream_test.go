@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+)
+
+// testAlerts is the One Call response of the fake upstream
+var testAlerts = models.AlertsData{
+	Alerts: []models.WeatherAlert{{SenderName: "IMD", Event: "Heat wave", Start: 1700000000, End: 1700086400}},
+}
+
+// newTestRefresher returns a refresher polling a fake upstream every interval;
+// the upstream reports temp(city) as the current temperature of each city,
+// where city is the normalised lower case name, and testAlerts everywhere
+func newTestRefresher(t *testing.T, interval time.Duration, temp func(city string) float64) *services.Refresher {
+	t.Helper()
+
+	return newUpstreamRefresher(t, interval, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/onecall" {
+			json.NewEncoder(w).Encode(testAlerts)
+			return
+		}
+		city := r.URL.Query().Get("q")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": city,
+			"main": map[string]float64{"temp": temp(city)},
+		})
+	})
+}
+
+// newUpstreamRefresher returns a refresher polling the given fake upstream every interval
+func newUpstreamRefresher(t *testing.T, interval time.Duration, handler http.HandlerFunc) *services.Refresher {
+	t.Helper()
+
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, AlertsURL: upstream.URL + "/onecall", Timeout: 5}}
+	refresher := services.NewRefresher(services.NewWeatherService(cfg, nil, nil, nil, nil), interval, interval)
+	t.Cleanup(refresher.Close)
+	return refresher
+}
+
+// cityTemp gives each test city a distinct, stable temperature
+func cityTemp(city string) float64 {
+	return float64(len(city))
+}
+
+// waitForUpdate polls until the refresher has published an update for city
+// with an ID of at least minID
+func waitForUpdate(t *testing.T, refresher *services.Refresher, city string, minID uint64) services.Update {
+	t.Helper()
+
+	topic := services.WeatherTopic(models.CityLocation(city))
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if update, ok := refresher.Latest(topic); ok && update.ID >= minID {
+			return update
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for update %d of %s", minID, city)
+	return services.Update{}
+}
+
+// sseEvent is a single event read from a stream
+type sseEvent struct {
+	id    uint64
+	event string
+	data  string
+}
+
+// openStream connects to a stream server and returns a reader over its body
+func openStream(t *testing.T, srv *httptest.Server, cities, lastEventID string) *bufio.Reader {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/stream/weather?cities="+cities, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// nextEvent reads the next event from a stream, skipping retry fields and comments
+func nextEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if ev.event != "" {
+				return ev
+			}
+		case strings.HasPrefix(line, "id: "):
+			ev.id, _ = strconv.ParseUint(strings.TrimPrefix(line, "id: "), 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStreamWeatherSnapshot(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	cities := []string{"London", "Paris", "Berlin", "Madrid", "Rome"}
+
+	// Keep the feeds alive so each city has a latest update to snapshot
+	var topics []services.Topic
+	for _, city := range cities {
+		topics = append(topics, services.WeatherTopic(models.CityLocation(city)))
+	}
+	sub := refresher.Subscribe(topics)
+	defer sub.Close()
+	for _, city := range cities {
+		waitForUpdate(t, refresher, city, 1)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(NewStreamHandler(refresher, 0, 0, time.Hour).StreamWeather))
+	t.Cleanup(srv.Close)
+
+	stream := openStream(t, srv, strings.Join(cities, ","), "")
+	seen := make(map[string]bool)
+	for range cities {
+		ev := nextEvent(t, stream)
+		var data models.WeatherData
+		if err := json.Unmarshal([]byte(ev.data), &data); err != nil {
+			t.Fatalf("decode event: %v", err)
+		}
+		seen[strings.ToLower(data.Name)] = true
+	}
+	for _, city := range cities {
+		if !seen[strings.ToLower(city)] {
+			t.Errorf("snapshot is missing %s", city)
+		}
+	}
+}
+
+func TestStreamWeatherResume(t *testing.T) {
+	var changing atomic.Int64
+	refresher := newTestRefresher(t, time.Millisecond, func(city string) float64 {
+		if city == "oslo" {
+			return float64(changing.Add(1))
+		}
+		return cityTemp(city)
+	})
+	cities := []string{"London", "Paris", "Berlin"}
+
+	var topics []services.Topic
+	for _, city := range cities {
+		topics = append(topics, services.WeatherTopic(models.CityLocation(city)))
+	}
+	sub := refresher.Subscribe(topics)
+	defer sub.Close()
+	for _, city := range cities {
+		waitForUpdate(t, refresher, city, 1)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(NewStreamHandler(refresher, 0, 0, time.Hour).StreamWeather))
+	t.Cleanup(srv.Close)
+
+	t.Run("replays missed updates", func(t *testing.T) {
+		stream := openStream(t, srv, strings.Join(cities, ","), "1")
+		for _, want := range []uint64{2, 3} {
+			if ev := nextEvent(t, stream); ev.id != want {
+				t.Fatalf("event id = %d, want %d", ev.id, want)
+			}
+		}
+	})
+
+	t.Run("ID ahead of sequence gets a snapshot", func(t *testing.T) {
+		stream := openStream(t, srv, "London", "1000000")
+		ev := nextEvent(t, stream)
+		if want := waitForUpdate(t, refresher, "London", 1).ID; ev.id != want {
+			t.Fatalf("event id = %d, want snapshot id %d", ev.id, want)
+		}
+	})
+
+	t.Run("ID older than history gets a snapshot", func(t *testing.T) {
+		// Oslo changes on every poll, pushing the London update out of the history
+		oslo := refresher.Subscribe([]services.Topic{services.WeatherTopic(models.CityLocation("Oslo"))})
+		defer oslo.Close()
+		waitForUpdate(t, refresher, "Oslo", 300)
+
+		stream := openStream(t, srv, "London", "1")
+		ev := nextEvent(t, stream)
+		if want := waitForUpdate(t, refresher, "London", 1).ID; ev.id != want {
+			t.Fatalf("event id = %d, want snapshot id %d", ev.id, want)
+		}
+	})
+}
+
+func TestStreamWeatherHeartbeat(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	srv := httptest.NewServer(http.HandlerFunc(NewStreamHandler(refresher, 0, 0, 20*time.Millisecond).StreamWeather))
+	t.Cleanup(srv.Close)
+
+	stream := openStream(t, srv, "London", "")
+	if line, err := stream.ReadString('\n'); err != nil || line != "retry: 20\n" {
+		t.Fatalf("first line = %q, %v; want retry: 20", line, err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		if line == ": heartbeat\n" {
+			return
+		}
+	}
+	t.Fatal("no heartbeat received")
+}
+
+func TestStreamWeatherConnectionLimit(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	srv := httptest.NewServer(http.HandlerFunc(NewStreamHandler(refresher, 1, 0, time.Hour).StreamWeather))
+	t.Cleanup(srv.Close)
+
+	openStream(t, srv, "London", "")
+
+	resp, err := http.Get(srv.URL + "/api/v1/stream/weather?cities=Paris")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/middleware"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/tracing"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+)
+
+// exportedSpan is the part of a stdout exporter span the tests inspect
+type exportedSpan struct {
+	Name        string
+	SpanKind    int
+	SpanContext struct{ TraceID, SpanID string }
+	Parent      struct{ TraceID, SpanID string }
+	Attributes  []struct {
+		Key   string
+		Value struct{ Value interface{} }
+	}
+	Status struct{ Code string }
+}
+
+func (s exportedSpan) attribute(key string) interface{} {
+	for _, attr := range s.Attributes {
+		if attr.Key == key {
+			return attr.Value.Value
+		}
+	}
+	return nil
+}
+
+// setupTestTracing installs tracing with the stdout exporter writing to buf and
+// restores the global provider and propagator when the test ends. The returned
+// function flushes exported spans into buf.
+func setupTestTracing(t *testing.T, buf *bytes.Buffer) func() {
+	t.Helper()
+
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	shutdown, err := tracing.Setup(context.Background(), config.TracingConfig{
+		Enabled:     true,
+		Exporter:    "stdout",
+		ServiceName: "weather-api-test",
+		SampleRatio: 1,
+	}, tracing.WithWriter(buf))
+	if err != nil {
+		t.Fatalf("tracing.Setup: %v", err)
+	}
+	return func() {
+		if err := shutdown(context.Background()); err != nil {
+			t.Fatalf("flush spans: %v", err)
+		}
+	}
+}
+
+// decodeSpans parses the spans written by the stdout exporter, keyed by name
+func decodeSpans(t *testing.T, buf *bytes.Buffer) map[string]exportedSpan {
+	t.Helper()
+
+	spans := make(map[string]exportedSpan)
+	dec := json.NewDecoder(buf)
+	for {
+		var span exportedSpan
+		if err := dec.Decode(&span); errors.Is(err, io.EOF) {
+			return spans
+		} else if err != nil {
+			t.Fatalf("decode span: %v", err)
+		}
+		spans[span.Name] = span
+	}
+}
+
+func TestTracingThroughUpstream(t *testing.T) {
+	const (
+		clientTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		clientSpanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name           string
+		upstreamStatus int
+		status         int
+		serverCode     string
+		clientCode     string
+	}{
+		{"success", http.StatusOK, http.StatusOK, "Unset", "Unset"},
+		{"upstream failure", http.StatusServiceUnavailable, http.StatusInternalServerError, "Error", "Error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			flush := setupTestTracing(t, &buf)
+
+			var traceparent string
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				traceparent = r.Header.Get("traceparent")
+				w.WriteHeader(tt.upstreamStatus)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"name": "London",
+					"main": map[string]float64{"temp": 12.5},
+				})
+			}))
+			defer upstream.Close()
+
+			cfg := &config.Config{
+				API:     config.APIConfig{BaseURL: upstream.URL, Timeout: 5},
+				Tracing: config.TracingConfig{PropagateUpstream: true},
+			}
+			r := mux.NewRouter()
+			r.Use(middleware.TracingMiddleware)
+			r.HandleFunc("/api/v1/weather/{city}", NewWeatherHandler(services.NewWeatherService(cfg, nil, nil, nil, nil)).GetCurrentWeather)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/weather/London", nil)
+			req.Header.Set("traceparent", "00-"+clientTraceID+"-"+clientSpanID+"-01")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.status, rec.Body)
+			}
+
+			flush()
+			spans := decodeSpans(t, &buf)
+
+			server, ok := spans["GET /api/v1/weather/{city}"]
+			if !ok {
+				t.Fatalf("no server span among %v", spans)
+			}
+			if server.SpanKind != 2 || server.SpanContext.TraceID != clientTraceID || server.Parent.SpanID != clientSpanID {
+				t.Errorf("server span kind %d in trace %s under %s, want a server span continuing the caller's trace",
+					server.SpanKind, server.SpanContext.TraceID, server.Parent.SpanID)
+			}
+			if got := server.attribute("http.route"); got != "/api/v1/weather/{city}" {
+				t.Errorf("server http.route = %v", got)
+			}
+			if got := server.attribute("http.response.status_code"); got != float64(tt.status) {
+				t.Errorf("server http.response.status_code = %v, want %d", got, tt.status)
+			}
+			if server.Status.Code != tt.serverCode {
+				t.Errorf("server status = %s, want %s", server.Status.Code, tt.serverCode)
+			}
+
+			handler := spans["WeatherHandler.GetCurrentWeather"]
+			if handler.Parent.SpanID != server.SpanContext.SpanID {
+				t.Errorf("handler span parent = %s, want the server span %s", handler.Parent.SpanID, server.SpanContext.SpanID)
+			}
+
+			client, ok := spans["openweathermap weather"]
+			if !ok {
+				t.Fatalf("no upstream span among %v", spans)
+			}
+			if client.SpanKind != 3 || client.SpanContext.TraceID != clientTraceID {
+				t.Errorf("upstream span kind %d in trace %s, want a client span in the caller's trace", client.SpanKind, client.SpanContext.TraceID)
+			}
+			if got := client.attribute("http.response.status_code"); got != float64(tt.upstreamStatus) {
+				t.Errorf("upstream http.response.status_code = %v, want %d", got, tt.upstreamStatus)
+			}
+			if client.Status.Code != tt.clientCode {
+				t.Errorf("upstream status = %s, want %s", client.Status.Code, tt.clientCode)
+			}
+
+			if want := "00-" + clientTraceID + "-" + client.SpanContext.SpanID + "-01"; traceparent != want {
+				t.Errorf("upstream traceparent = %q, want %q", traceparent, want)
+			}
+		})
+	}
+}
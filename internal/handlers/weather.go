@@ -1,13 +1,24 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
 	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/tracing"
 	"github.com/ANAS727189/weather-project/internal/utils"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WeatherHandler handles weather-related HTTP requests
@@ -27,28 +38,74 @@ func (h *WeatherHandler) GetCurrentWeather(w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	city := vars["city"]
 
+	ctx, span := tracing.Tracer().Start(r.Context(), "WeatherHandler.GetCurrentWeather",
+		trace.WithAttributes(attribute.String("weather.city", city)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	if city == "" {
-		utils.WriteErrorResponse(w, "City parameter is required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, "City parameter is required", http.StatusBadRequest)
 		return
 	}
 
 	// Validate city name
 	if err := validateCityName(city); err != nil {
-		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	data, err := h.weatherService.GetCurrentWeather(city)
+	data, err := h.weatherService.GetCurrentWeather(r.Context(), city)
 	if err != nil {
-		if strings.Contains(err.Error(), "city not found") {
-			utils.WriteErrorResponse(w, fmt.Sprintf("City '%s' not found", city), http.StatusNotFound)
-		} else {
-			utils.WriteErrorResponse(w, err.Error(), http.StatusInternalServerError)
-		}
+		h.writeServiceError(w, r, city, err)
 		return
 	}
 
-	utils.WriteSuccessResponse(w, data)
+	setWeatherCacheHeaders(w, data)
+	utils.WriteSuccessResponse(w, r, data)
+}
+
+// maxBatchCities bounds the upstream calls a single batch request can make
+const maxBatchCities = 20
+
+// GetBatchWeather handles GET /api/v1/weather?cities=a,b,c. Cities that fail
+// are reported in their result rather than failing the whole batch.
+func (h *WeatherHandler) GetBatchWeather(w http.ResponseWriter, r *http.Request) {
+	cities := parseCityList(r.URL.Query().Get("cities"))
+
+	ctx, span := tracing.Tracer().Start(r.Context(), "WeatherHandler.GetBatchWeather",
+		trace.WithAttributes(attribute.StringSlice("weather.cities", cities)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	if len(cities) == 0 {
+		utils.WriteErrorResponse(w, r, "cities parameter is required", http.StatusBadRequest)
+		return
+	}
+	if len(cities) > maxBatchCities {
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("at most %d cities can be requested at once", maxBatchCities), http.StatusBadRequest)
+		return
+	}
+	for _, city := range cities {
+		if err := validateCityName(city); err != nil {
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("Invalid city %q: %v", city, err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	resp := &models.BatchWeatherResponse{Results: make([]models.BatchWeatherResult, 0, len(cities))}
+	for _, city := range cities {
+		result := models.BatchWeatherResult{City: city}
+		data, err := h.weatherService.GetCurrentWeather(r.Context(), city)
+		if err != nil {
+			result.Error = h.batchError(r, city, err)
+		} else {
+			obs := models.NewObservation(models.CityLocation(city), data, data.FetchedAt)
+			result.Weather = &obs
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	resp.Count = len(resp.Results)
+	utils.WriteSuccessResponse(w, r, resp)
 }
 
 // GetForecast handles GET /api/v1/forecast/{city}
@@ -56,28 +113,56 @@ func (h *WeatherHandler) GetForecast(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	city := vars["city"]
 
+	ctx, span := tracing.Tracer().Start(r.Context(), "WeatherHandler.GetForecast",
+		trace.WithAttributes(attribute.String("weather.city", city)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
 	if city == "" {
-		utils.WriteErrorResponse(w, "City parameter is required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, "City parameter is required", http.StatusBadRequest)
 		return
 	}
 
 	// Validate city name
 	if err := validateCityName(city); err != nil {
-		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	data, err := h.weatherService.GetForecast(city)
+	data, err := h.weatherService.GetForecast(r.Context(), city)
 	if err != nil {
-		if strings.Contains(err.Error(), "city not found") {
-			utils.WriteErrorResponse(w, fmt.Sprintf("City '%s' not found", city), http.StatusNotFound)
-		} else {
-			utils.WriteErrorResponse(w, err.Error(), http.StatusInternalServerError)
-		}
+		h.writeServiceError(w, r, city, err)
 		return
 	}
 
-	utils.WriteSuccessResponse(w, data)
+	// Forecasts have no observation time, so they are dated by when they were fetched
+	utils.SetCacheHeaders(w, data.FetchedAt, time.Until(data.ExpiresAt))
+	utils.WriteSuccessResponse(w, r, data)
+}
+
+// GetAlerts handles GET /api/v1/alerts/{city}
+func (h *WeatherHandler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	city := vars["city"]
+
+	ctx, span := tracing.Tracer().Start(r.Context(), "WeatherHandler.GetAlerts",
+		trace.WithAttributes(attribute.String("weather.city", city)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	if err := validateCityName(city); err != nil {
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.weatherService.GetAlerts(r.Context(), city)
+	if err != nil {
+		h.writeServiceError(w, r, city, err)
+		return
+	}
+
+	utils.SetCacheHeaders(w, data.FetchedAt, time.Until(data.ExpiresAt))
+	utils.WriteSuccessResponse(w, r, data)
 }
 
 // GetCurrentWeatherLegacy handles GET /weather/{city} (legacy endpoint)
@@ -85,23 +170,72 @@ func (h *WeatherHandler) GetCurrentWeatherLegacy(w http.ResponseWriter, r *http.
 	city := strings.TrimPrefix(r.URL.Path, "/weather/")
 
 	if city == "" {
-		utils.WriteErrorResponse(w, "City parameter is required", http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, "City parameter is required", http.StatusBadRequest)
 		return
 	}
 
 	// Validate city name
 	if err := validateCityName(city); err != nil {
-		utils.WriteErrorResponse(w, err.Error(), http.StatusBadRequest)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	data, err := h.weatherService.GetCurrentWeather(city)
+	data, err := h.weatherService.GetCurrentWeather(r.Context(), city)
 	if err != nil {
-		utils.WriteErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	utils.WriteSuccessResponse(w, data)
+	setWeatherCacheHeaders(w, data)
+	utils.WriteSuccessResponse(w, r, data)
+}
+
+// setWeatherCacheHeaders dates current weather by its observation time and
+// lets clients cache it until the server-side cache entry expires
+func setWeatherCacheHeaders(w http.ResponseWriter, data *models.WeatherData) {
+	lastModified := data.FetchedAt
+	if data.Dt != 0 {
+		lastModified = time.Unix(data.Dt, 0)
+	}
+	utils.SetCacheHeaders(w, lastModified, time.Until(data.ExpiresAt))
+}
+
+// writeServiceError maps weather service errors to HTTP responses
+func (h *WeatherHandler) writeServiceError(w http.ResponseWriter, r *http.Request, city string, err error) {
+	trace.SpanFromContext(r.Context()).RecordError(err)
+	switch {
+	case strings.Contains(err.Error(), "city not found"):
+		utils.WriteErrorResponse(w, r, fmt.Sprintf("City '%s' not found", city), http.StatusNotFound)
+	case errors.Is(err, services.ErrAlertsUnavailable):
+		utils.WriteErrorResponse(w, r, "Weather alerts are not enabled", http.StatusNotFound)
+	case errors.Is(err, quota.ErrBudgetExhausted):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(h.weatherService.RetryAfter().Seconds()))))
+		utils.WriteErrorResponse(w, r, "Weather data is temporarily unavailable, please try again later", http.StatusServiceUnavailable)
+	case errors.Is(err, breaker.ErrOpen):
+		// A breaker forced open has no cooldown to wait for
+		if retryAfter := h.weatherService.BreakerRetryAfter(); retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		}
+		utils.WriteErrorResponse(w, r, "Weather data is temporarily unavailable, please try again later", http.StatusServiceUnavailable)
+	default:
+		slog.ErrorContext(r.Context(), "weather request failed", "city", city, "error", err)
+		utils.WriteErrorResponse(w, r, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// batchError describes why one city of a batch failed, worded like the
+// responses writeServiceError gives for a single city
+func (h *WeatherHandler) batchError(r *http.Request, city string, err error) string {
+	trace.SpanFromContext(r.Context()).RecordError(err)
+	switch {
+	case strings.Contains(err.Error(), "city not found"):
+		return fmt.Sprintf("City '%s' not found", city)
+	case errors.Is(err, quota.ErrBudgetExhausted), errors.Is(err, breaker.ErrOpen):
+		return "Weather data is temporarily unavailable, please try again later"
+	default:
+		slog.ErrorContext(r.Context(), "weather request failed", "city", city, "error", err)
+		return err.Error()
+	}
 }
 
 // validateCityName validates the city name format
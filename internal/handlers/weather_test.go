@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+func TestWeatherHandlerBudgetExhausted(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("upstream called with an exhausted budget: %s", r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	budget := quota.NewBudget(1, 100, 0)
+	if err := budget.Acquire(quota.PriorityUser); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}
+	h := NewWeatherHandler(services.NewWeatherService(cfg, nil, nil, budget, nil))
+
+	tests := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+	}{
+		{"current weather", "/api/v1/weather/London", h.GetCurrentWeather},
+		{"forecast", "/api/v1/forecast/London", h.GetForecast},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"city": "London"})
+			rec := httptest.NewRecorder()
+
+			tt.handler(rec, req)
+
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503; body: %s", rec.Code, rec.Body)
+			}
+			retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+			if err != nil || retryAfter < 1 || retryAfter > 60 {
+				t.Errorf("Retry-After = %q, want 1-60 seconds until the minute resets", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+// newTestWeatherHandler returns a weather handler whose upstream knows every
+// city except Atlantis
+func newTestWeatherHandler(t *testing.T) *WeatherHandler {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		city := r.URL.Query().Get("q")
+		if strings.EqualFold(city, "Atlantis") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name":  city,
+			"coord": map[string]float64{"lon": -0.13, "lat": 51.51},
+			"main":  map[string]float64{"temp": float64(len(city))},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}
+	return NewWeatherHandler(services.NewWeatherService(cfg, nil, nil, nil, nil))
+}
+
+// getBatch requests the current weather of cities rendered in format
+func getBatch(h *WeatherHandler, cities, format string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/weather?cities="+url.QueryEscape(cities), nil)
+	req = req.WithContext(utils.WithFormat(req.Context(), format))
+	rec := httptest.NewRecorder()
+	h.GetBatchWeather(rec, req)
+	return rec
+}
+
+func TestGetBatchWeather(t *testing.T) {
+	h := newTestWeatherHandler(t)
+
+	rec := getBatch(h, "London, Paris,london,Atlantis", utils.FormatJSON)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	var resp models.BatchWeatherResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 3 || len(resp.Results) != 3 {
+		t.Fatalf("results = %+v, want London, Paris and Atlantis once each", resp.Results)
+	}
+	for i, want := range []struct {
+		city string
+		temp float64
+		err  string
+	}{
+		{"London", 6, ""},
+		{"Paris", 5, ""},
+		{"Atlantis", 0, "City 'Atlantis' not found"},
+	} {
+		got := resp.Results[i]
+		if got.City != want.city || got.Error != want.err {
+			t.Errorf("result %d = %+v, want city %q with error %q", i, got, want.city, want.err)
+		}
+		if want.err == "" && (got.Weather == nil || got.Weather.Temp != want.temp || got.Weather.Location != "city:"+strings.ToLower(want.city)) {
+			t.Errorf("result %d weather = %+v, want %v degrees for %s", i, got.Weather, want.temp, want.city)
+		}
+		if want.err != "" && got.Weather != nil {
+			t.Errorf("result %d has weather %+v alongside an error", i, got.Weather)
+		}
+	}
+}
+
+func TestGetBatchWeatherTabular(t *testing.T) {
+	h := newTestWeatherHandler(t)
+
+	t.Run("csv", func(t *testing.T) {
+		rec := getBatch(h, "London,Atlantis", utils.FormatCSV)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+		}
+		rows, err := csv.NewReader(rec.Body).ReadAll()
+		if err != nil {
+			t.Fatalf("parse CSV: %v", err)
+		}
+		if len(rows) != 3 {
+			t.Fatalf("got %d lines, want a header and one row per city:\n%v", len(rows), rows)
+		}
+		column := make(map[string]int)
+		for i, name := range rows[0] {
+			column[name] = i
+		}
+		for _, name := range []string{"city", "weather.temp", "error"} {
+			if _, ok := column[name]; !ok {
+				t.Fatalf("header %v has no %q column", rows[0], name)
+			}
+		}
+		if got := rows[1][column["city"]] + "/" + rows[1][column["weather.temp"]] + "/" + rows[1][column["error"]]; got != "London/6/" {
+			t.Errorf("London row = %q, want London/6/", got)
+		}
+		if got := rows[2][column["city"]] + "/" + rows[2][column["weather.temp"]] + "/" + rows[2][column["error"]]; got != "Atlantis//City 'Atlantis' not found" {
+			t.Errorf("Atlantis row = %q, want only an error", got)
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		rec := getBatch(h, "London,Paris,Atlantis", utils.FormatNDJSON)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+		}
+		var cities []string
+		scanner := bufio.NewScanner(rec.Body)
+		for scanner.Scan() {
+			var result models.BatchWeatherResult
+			if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+				t.Fatalf("line %q: %v", scanner.Text(), err)
+			}
+			cities = append(cities, result.City)
+		}
+		if got := strings.Join(cities, ","); got != "London,Paris,Atlantis" {
+			t.Errorf("lines = %s, want one per city in request order", got)
+		}
+	})
+}
+
+func TestGetBatchWeatherValidation(t *testing.T) {
+	h := newTestWeatherHandler(t)
+	tooMany := make([]string, maxBatchCities+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("City%d", i)
+	}
+
+	tests := []struct {
+		name   string
+		cities string
+	}{
+		{"missing", ""},
+		{"only separators", " , ,"},
+		{"too many", strings.Join(tooMany, ",")},
+		{"invalid name", "London,<script>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := getBatch(h, tt.cities, utils.FormatJSON); rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400; body: %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestGetAlerts(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/weather":
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": "London", "coord": map[string]float64{"lon": -0.13, "lat": 51.51}})
+		case "/onecall":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"lat": 51.51,
+				"lon": -0.13,
+				"alerts": []map[string]interface{}{
+					{"event": "Wind", "polygons": [][][][]float64{{{{0, 50}, {1, 50}, {1, 51}, {0, 50}}}}},
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	tests := []struct {
+		name       string
+		alertsURL  string
+		format     string
+		wantStatus int
+		wantBody   string
+		skipBody   string
+	}{
+		{"not configured", "", utils.FormatJSON, http.StatusNotFound, "not enabled", ""},
+		{"json keeps areas", upstream.URL + "/onecall", utils.FormatJSON, http.StatusOK, `"polygons":[[[[0,50]`, ""},
+		{"csv drops areas", upstream.URL + "/onecall", utils.FormatCSV, http.StatusOK, "Wind", "polygons"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, AlertsURL: tt.alertsURL, Timeout: 5}}
+			h := NewWeatherHandler(services.NewWeatherService(cfg, nil, nil, nil, nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/alerts/London", nil)
+			req = mux.SetURLVars(req, map[string]string{"city": "London"})
+			req = req.WithContext(utils.WithFormat(req.Context(), tt.format))
+			rec := httptest.NewRecorder()
+			h.GetAlerts(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body, tt.wantBody)
+			}
+			if tt.skipBody != "" && strings.Contains(rec.Body.String(), tt.skipBody) {
+				t.Errorf("body = %s, want no %s", rec.Body, tt.skipBody)
+			}
+		})
+	}
+}
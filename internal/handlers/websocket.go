@@ -0,0 +1,374 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// socketWriteWait is the time allowed to write a single frame
+	socketWriteWait = 10 * time.Second
+
+	// socketMaxMessageSize is the largest client message accepted
+	socketMaxMessageSize = 4096
+
+	// socketQueueSize is the number of control frames queued per connection
+	socketQueueSize = 16
+
+	// socketMaxDropped is how many updates a connection may miss before it is closed
+	socketMaxDropped = 64
+)
+
+// SocketHandler handles WebSocket subscriptions for dashboards
+type SocketHandler struct {
+	refresher      *services.Refresher
+	upgrader       websocket.Upgrader
+	maxConnections int
+	maxLocations   int
+	pingInterval   time.Duration
+	active         atomic.Int64
+
+	mu      sync.Mutex
+	closing bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewSocketHandler creates a new WebSocket handler. allowsOrigin decides which
+// browser origins may connect and is consulted on every upgrade, so it can
+// follow configuration reloads.
+func NewSocketHandler(refresher *services.Refresher, allowsOrigin func(origin string) bool, maxConnections, maxLocations int, pingInterval time.Duration) *SocketHandler {
+	return &SocketHandler{
+		refresher: refresher,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 4096,
+			CheckOrigin:     originChecker(allowsOrigin),
+		},
+		maxConnections: maxConnections,
+		maxLocations:   maxLocations,
+		pingInterval:   pingInterval,
+		done:           make(chan struct{}),
+	}
+}
+
+// socketConn holds the state of a single WebSocket connection
+type socketConn struct {
+	conn      *websocket.Conn
+	sub       *services.Subscription
+	queue     chan models.SocketMessage
+	locations map[string][]services.Topic
+}
+
+// Subscribe handles GET /api/v1/ws
+func (h *SocketHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		utils.WriteErrorResponse(w, r, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.wg.Add(1)
+	h.mu.Unlock()
+	defer h.wg.Done()
+
+	if n := h.active.Add(1); h.maxConnections > 0 && n > int64(h.maxConnections) {
+		h.active.Add(-1)
+		utils.WriteErrorResponse(w, r, "too many active connections, try again later", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.active.Add(-1)
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written an error response
+		return
+	}
+	defer conn.Close()
+
+	sc := &socketConn{
+		conn:      conn,
+		sub:       h.refresher.Subscribe(nil),
+		queue:     make(chan models.SocketMessage, socketQueueSize),
+		locations: make(map[string][]services.Topic),
+	}
+	defer sc.sub.Close()
+
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		h.readLoop(r.Context(), sc)
+	}()
+
+	h.writeLoop(sc, readerDone)
+}
+
+// Shutdown sends a close frame to every open connection and waits for them to finish
+func (h *SocketHandler) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closing {
+		h.closing = true
+		close(h.done)
+	}
+	h.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		h.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// readLoop processes subscribe and unsubscribe messages until the client goes away
+func (h *SocketHandler) readLoop(ctx context.Context, sc *socketConn) {
+	pongWait := 2 * h.pingInterval
+	sc.conn.SetReadLimit(socketMaxMessageSize)
+	sc.conn.SetReadDeadline(time.Now().Add(pongWait))
+	sc.conn.SetPongHandler(func(string) error {
+		return sc.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var req models.SocketRequest
+		if err := sc.conn.ReadJSON(&req); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				if !sc.enqueue(models.SocketMessage{Type: "error", Message: "malformed message"}) {
+					return
+				}
+				continue
+			}
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.WarnContext(ctx, "websocket: read error", "error", err)
+			}
+			return
+		}
+
+		reply := h.handleRequest(sc, req)
+		if !sc.enqueue(reply) {
+			return
+		}
+	}
+}
+
+// handleRequest applies a single client message to the connection's subscriptions
+func (h *SocketHandler) handleRequest(sc *socketConn, req models.SocketRequest) models.SocketMessage {
+	loc, err := requestLocation(req)
+	if err != nil {
+		return models.SocketMessage{Type: "error", Message: err.Error()}
+	}
+
+	switch req.Type {
+	case "subscribe":
+		topics, err := requestTopics(loc, req.Topics)
+		if err != nil {
+			return models.SocketMessage{Type: "error", Location: &loc, Message: err.Error()}
+		}
+		key := loc.Key()
+		if _, ok := sc.locations[key]; !ok && h.maxLocations > 0 && len(sc.locations) >= h.maxLocations {
+			return models.SocketMessage{
+				Type:     "error",
+				Location: &loc,
+				Message:  fmt.Sprintf("at most %d locations can be subscribed per connection", h.maxLocations),
+			}
+		}
+		for _, topic := range sc.locations[key] {
+			sc.sub.Remove(topic)
+		}
+		sc.locations[key] = topics
+		for _, topic := range topics {
+			sc.sub.Add(topic)
+			if update, ok := h.refresher.Latest(topic); ok {
+				if !sc.enqueue(updateMessage(update)) {
+					break
+				}
+			}
+		}
+		return models.SocketMessage{Type: "subscribed", Location: &loc, Topics: topicKinds(topics)}
+
+	case "unsubscribe":
+		for _, topic := range sc.locations[loc.Key()] {
+			sc.sub.Remove(topic)
+		}
+		delete(sc.locations, loc.Key())
+		return models.SocketMessage{Type: "unsubscribed", Location: &loc}
+
+	default:
+		return models.SocketMessage{Type: "error", Message: fmt.Sprintf("unknown message type %q", req.Type)}
+	}
+}
+
+// writeLoop is the only writer on the connection; it forwards updates, replies and pings
+func (h *SocketHandler) writeLoop(sc *socketConn, readerDone <-chan struct{}) {
+	ticker := time.NewTicker(h.pingInterval)
+	defer ticker.Stop()
+
+	sent := make(map[services.Topic]uint64)
+	var reportedDrops uint64
+
+	for {
+		var msg models.SocketMessage
+		select {
+		case <-readerDone:
+			return
+
+		case <-h.done:
+			sc.close(websocket.CloseGoingAway, "server shutting down")
+			return
+
+		case update := <-sc.sub.Updates():
+			if update.ID <= sent[update.Topic] {
+				continue
+			}
+			sent[update.Topic] = update.ID
+			msg = updateMessage(update)
+
+		case msg = <-sc.queue:
+			if msg.ID != 0 {
+				topic := services.Topic{Kind: msg.Type, Location: *msg.Location}
+				if msg.ID <= sent[topic] {
+					continue
+				}
+				sent[topic] = msg.ID
+			}
+
+		case <-ticker.C:
+			sc.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			if err := sc.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		}
+
+		// Tell clients that fell behind so they can resync, and cut off hopeless ones
+		if dropped := sc.sub.Dropped(); dropped > reportedDrops {
+			if dropped >= socketMaxDropped {
+				sc.close(websocket.CloseTryAgainLater, "client too slow")
+				return
+			}
+			if !sc.write(models.SocketMessage{Type: "lagged", Dropped: dropped - reportedDrops}) {
+				return
+			}
+			reportedDrops = dropped
+		}
+
+		if !sc.write(msg) {
+			return
+		}
+	}
+}
+
+// enqueue queues a message for the writer, reporting false if the client is not keeping up
+func (sc *socketConn) enqueue(msg models.SocketMessage) bool {
+	select {
+	case sc.queue <- msg:
+		return true
+	default:
+		sc.close(websocket.ClosePolicyViolation, "too many pending messages")
+		return false
+	}
+}
+
+// write sends a JSON frame with a write deadline
+func (sc *socketConn) write(msg models.SocketMessage) bool {
+	sc.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return sc.conn.WriteJSON(msg) == nil
+}
+
+// close sends a close frame; it is safe to call alongside other writers
+func (sc *socketConn) close(code int, reason string) {
+	deadline := time.Now().Add(socketWriteWait)
+	sc.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+}
+
+// updateMessage converts a refresher update into a client frame
+func updateMessage(update services.Update) models.SocketMessage {
+	loc := update.Topic.Location
+	return models.SocketMessage{
+		Type:     update.Topic.Kind,
+		ID:       update.ID,
+		Location: &loc,
+		Data:     update.Data,
+	}
+}
+
+// requestLocation extracts and validates the location of a client message
+func requestLocation(req models.SocketRequest) (models.Location, error) {
+	if req.Lat != nil || req.Lon != nil {
+		if req.Lat == nil || req.Lon == nil {
+			return models.Location{}, fmt.Errorf("both lat and lon are required")
+		}
+		if *req.Lat < -90 || *req.Lat > 90 || *req.Lon < -180 || *req.Lon > 180 {
+			return models.Location{}, fmt.Errorf("coordinates out of range")
+		}
+		return models.CoordLocation(*req.Lat, *req.Lon), nil
+	}
+	if err := validateCityName(req.City); err != nil {
+		return models.Location{}, err
+	}
+	return models.CityLocation(req.City), nil
+}
+
+// requestTopics maps requested topic names to refresher topics, defaulting to
+// weather and forecast. Alerts need a One Call subscription upstream, so
+// clients ask for them explicitly.
+func requestTopics(loc models.Location, kinds []string) ([]services.Topic, error) {
+	if len(kinds) == 0 {
+		kinds = []string{services.TopicWeather, services.TopicForecast}
+	}
+
+	var topics []services.Topic
+	seen := make(map[string]bool)
+	for _, kind := range kinds {
+		if seen[kind] {
+			continue
+		}
+		seen[kind] = true
+		switch kind {
+		case services.TopicWeather:
+			topics = append(topics, services.WeatherTopic(loc))
+		case services.TopicForecast:
+			topics = append(topics, services.ForecastTopic(loc))
+		case services.TopicAlerts:
+			topics = append(topics, services.AlertsTopic(loc))
+		default:
+			return nil, fmt.Errorf("unknown topic %q", kind)
+		}
+	}
+	return topics, nil
+}
+
+// topicKinds lists the kinds of the given topics
+func topicKinds(topics []services.Topic) []string {
+	kinds := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		kinds = append(kinds, topic.Kind)
+	}
+	return kinds
+}
+
+// originChecker allows WebSocket upgrades from non-browser clients, which
+// send no Origin, and from origins allowsOrigin accepts
+func originChecker(allowsOrigin func(origin string) bool) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || allowsOrigin(origin)
+	}
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/gorilla/websocket"
+)
+
+// newTestSocketServer serves h on a test server and returns it
+func newTestSocketServer(t *testing.T, h *SocketHandler) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(h.Subscribe))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// dialSocket opens a WebSocket connection to a test server
+func dialSocket(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil reads frames until one of the given type arrives
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) models.SocketMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg models.SocketMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s frame: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+// readCloseCode reads frames until the server closes the connection and
+// returns the close code it sent
+func readCloseCode(t *testing.T, conn *websocket.Conn) int {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *websocket.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("read: %v, want a close frame", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func TestSocketSubscribe(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	h := NewSocketHandler(refresher, func(string) bool { return false }, 0, 2, time.Minute)
+	conn := dialSocket(t, newTestSocketServer(t, h))
+
+	tests := []struct {
+		name     string
+		req      models.SocketRequest
+		wantType string
+		wantMsg  string
+	}{
+		{"weather only", models.SocketRequest{Type: "subscribe", City: "London", Topics: []string{"weather"}}, "subscribed", ""},
+		{"all topics", models.SocketRequest{Type: "subscribe", City: "Paris"}, "subscribed", ""},
+		{"location limit", models.SocketRequest{Type: "subscribe", City: "Berlin"}, "error", "at most 2 locations can be subscribed per connection"},
+		{"unsubscribe", models.SocketRequest{Type: "unsubscribe", City: "Paris"}, "unsubscribed", ""},
+		{"unknown topic", models.SocketRequest{Type: "subscribe", City: "Berlin", Topics: []string{"radar"}}, "error", `unknown topic "radar"`},
+		{"half coordinates", models.SocketRequest{Type: "subscribe", Lat: new(float64)}, "error", "both lat and lon are required"},
+		{"unknown type", models.SocketRequest{Type: "publish", City: "London"}, "error", `unknown message type "publish"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := conn.WriteJSON(tt.req); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			// Skip data frames pushed for earlier subscriptions
+			var msg models.SocketMessage
+			for msg.Type == "" || msg.Type == "weather" || msg.Type == "forecast" || msg.Type == "alerts" {
+				msg = readFrame(t, conn)
+			}
+			if msg.Type != tt.wantType || msg.Message != tt.wantMsg {
+				t.Fatalf("reply = %s %q, want %s %q", msg.Type, msg.Message, tt.wantType, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// readFrame reads the next frame
+func readFrame(t *testing.T, conn *websocket.Conn) models.SocketMessage {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg models.SocketMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+func TestSocketDeliversUpdates(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	h := NewSocketHandler(refresher, func(string) bool { return false }, 0, 0, time.Minute)
+	conn := dialSocket(t, newTestSocketServer(t, h))
+
+	if err := conn.WriteJSON(models.SocketRequest{Type: "subscribe", City: "London", Topics: []string{"weather"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	msg := readUntil(t, conn, "weather")
+	if msg.ID == 0 || msg.Location == nil || msg.Location.City != "london" {
+		t.Fatalf("weather frame = %+v, want an update for london", msg)
+	}
+
+	// A second connection is sent the latest update straight away
+	other := dialSocket(t, newTestSocketServer(t, h))
+	if err := other.WriteJSON(models.SocketRequest{Type: "subscribe", City: "London", Topics: []string{"weather"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := readUntil(t, other, "weather"); got.ID != msg.ID {
+		t.Errorf("latest update ID = %d, want %d", got.ID, msg.ID)
+	}
+}
+
+func TestSocketDeliversAlerts(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	h := NewSocketHandler(refresher, func(string) bool { return false }, 0, 0, time.Minute)
+	conn := dialSocket(t, newTestSocketServer(t, h))
+
+	if err := conn.WriteJSON(models.SocketRequest{Type: "subscribe", City: "Delhi", Topics: []string{"alerts"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if ack := readUntil(t, conn, "subscribed"); len(ack.Topics) != 1 || ack.Topics[0] != "alerts" {
+		t.Fatalf("subscribed topics = %v, want [alerts]", ack.Topics)
+	}
+
+	msg := readUntil(t, conn, "alerts")
+	if msg.Location == nil || msg.Location.City != "delhi" {
+		t.Fatalf("alerts frame location = %+v, want delhi", msg.Location)
+	}
+	raw, err := json.Marshal(msg.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var alerts models.AlertsData
+	if err := json.Unmarshal(raw, &alerts); err != nil {
+		t.Fatalf("decode alerts: %v", err)
+	}
+	if len(alerts.Alerts) != 1 || alerts.Alerts[0].Event != "Heat wave" {
+		t.Errorf("alerts = %+v, want the upstream heat wave", alerts.Alerts)
+	}
+}
+
+func TestSocketClosesLaggingClients(t *testing.T) {
+	// Every poll returns a new, large payload so a client that stops reading
+	// fills the socket buffers and then misses updates
+	var polls atomic.Int64
+	padding := strings.Repeat("x", 256<<10)
+	refresher := newUpstreamRefresher(t, time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": padding,
+			"main": map[string]float64{"temp": float64(polls.Add(1))},
+		})
+	})
+	h := NewSocketHandler(refresher, func(string) bool { return false }, 0, 0, time.Minute)
+	conn := dialSocket(t, newTestSocketServer(t, h))
+
+	if err := conn.WriteJSON(models.SocketRequest{Type: "subscribe", City: "London", Topics: []string{"weather"}}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	topic := services.WeatherTopic(models.CityLocation("London"))
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if update, ok := refresher.Latest(topic); ok && update.ID > 4*socketMaxDropped {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if code := readCloseCode(t, conn); code != websocket.CloseTryAgainLater {
+		t.Fatalf("close code = %d, want %d", code, websocket.CloseTryAgainLater)
+	}
+}
+
+func TestSocketShutdown(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	h := NewSocketHandler(refresher, func(string) bool { return false }, 0, 0, time.Minute)
+	srv := newTestSocketServer(t, h)
+	conn := dialSocket(t, srv)
+
+	if err := conn.WriteJSON(models.SocketRequest{Type: "subscribe", City: "London"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	readUntil(t, conn, "subscribed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if code := readCloseCode(t, conn); code != websocket.CloseGoingAway {
+		t.Errorf("close code = %d, want %d", code, websocket.CloseGoingAway)
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status after shutdown = %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestSocketOriginCheck(t *testing.T) {
+	refresher := newTestRefresher(t, time.Hour, cityTemp)
+	h := NewSocketHandler(refresher, func(origin string) bool { return origin == "https://allowed.example" }, 0, 0, time.Minute)
+	srv := newTestSocketServer(t, h)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	tests := []struct {
+		origin  string
+		wantErr bool
+	}{
+		{"", false},
+		{"https://allowed.example", false},
+		{"https://evil.example", true},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, header)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("origin %q: err = %v, wantErr %v", tt.origin, err, tt.wantErr)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weather"
+
+// registry holds every collector exposed on the metrics endpoint
+var registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts served requests by route template, method and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// HTTPDuration observes request latency by route template, method and status
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route template, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// HTTPInFlight tracks requests currently being served, including open streams
+	HTTPInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served.",
+	})
+
+	// UpstreamDuration observes OpenWeatherMap call latency by endpoint and outcome
+	UpstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "OpenWeatherMap request latency, by endpoint and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "outcome"})
+
+	// UpstreamRetries counts OpenWeatherMap calls retried after a transport error or 5xx answer
+	UpstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "OpenWeatherMap requests retried, by endpoint.",
+	}, []string{"endpoint"})
+
+	// CacheLookups counts response cache lookups by kind and result (hit, miss,
+	// stale, or coalesced when a miss was filled by another caller's refresh)
+	CacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Response cache lookups, by data kind and result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		HTTPInFlight,
+		UpstreamDuration,
+		UpstreamRetries,
+		CacheLookups,
+	)
+}
+
+// Handler serves all registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
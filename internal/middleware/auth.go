@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/gorilla/mux"
+)
+
+// AuthMiddleware requires a valid API key or bearer token and attaches the
+// consumer to the request context. Requests to exempt paths pass through
+// unauthenticated.
+func AuthMiddleware(authenticator *auth.Authenticator, exemptPaths []string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(exemptPaths))
+	for _, path := range exemptPaths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			consumer, err := authenticator.Authenticate(r)
+			if err != nil {
+				for _, scheme := range authenticator.Schemes() {
+					w.Header().Add("WWW-Authenticate", scheme)
+				}
+				if errors.Is(err, auth.ErrNoCredentials) {
+					utils.WriteErrorResponse(w, r, "Authentication is required", http.StatusUnauthorized)
+					return
+				}
+				slog.InfoContext(r.Context(), "authentication failed", "path", r.URL.Path, "error", err)
+				utils.WriteErrorResponse(w, r, "Invalid credentials", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithConsumer(r.Context(), consumer)))
+		})
+	}
+}
+
+// adminTokenConsumer identifies callers authenticated by the admin token
+var adminTokenConsumer = &auth.Consumer{ID: "admin-token", Name: "Admin listener token", Scopes: []string{"admin"}}
+
+// AdminTokenMiddleware requires "Authorization: Bearer <token>" matching the
+// admin listener's token and attaches the admin token consumer to the request
+// context. An empty token disables the check.
+func AdminTokenMiddleware(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				utils.WriteErrorResponse(w, r, "Invalid admin token", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithConsumer(r.Context(), adminTokenConsumer)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose authenticated consumer lacks scope
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			consumer, ok := auth.ConsumerFromContext(r.Context())
+			if !ok || !consumer.HasScope(scope) {
+				utils.WriteErrorResponse(w, r, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRouteScopes rejects requests to named routes whose authenticated
+// consumer lacks the scope configured for that route name
+func RequireRouteScopes(scopes map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			scope, ok := scopes[route.GetName()]
+			if !ok || scope == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			consumer, ok := auth.ConsumerFromContext(r.Context())
+			if !ok || !consumer.HasScope(scope) {
+				utils.WriteErrorResponse(w, r, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
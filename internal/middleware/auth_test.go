@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	authenticator, err := auth.NewAuthenticator(config.AuthConfig{
+		HeaderName: "X-API-Key",
+		QueryParam: "api_key",
+		APIKeys: []config.APIKeyConfig{
+			{ID: "portal", Hash: auth.HashKey("portal-key")},
+			{ID: "mobile", Hash: auth.HashKey("mobile-key")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewAuthenticator: %v", err)
+	}
+
+	handler := AuthMiddleware(authenticator, []string{"/api/v1/health"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer, ok := auth.ConsumerFromContext(r.Context())
+		if !ok {
+			w.Write([]byte("anonymous"))
+			return
+		}
+		w.Write([]byte(consumer.ID))
+	}))
+
+	tests := []struct {
+		name        string
+		target      string
+		header      string
+		wantStatus  int
+		wantBody    string
+		wantMessage string
+	}{
+		{"header", "/api/v1/weather/London", "portal-key", http.StatusOK, "portal", ""},
+		{"query parameter", "/api/v1/weather/London?api_key=mobile-key", "", http.StatusOK, "mobile", ""},
+		{"header wins over query", "/api/v1/weather/London?api_key=mobile-key", "portal-key", http.StatusOK, "portal", ""},
+		{"missing key", "/api/v1/weather/London", "", http.StatusUnauthorized, "", "Authentication is required"},
+		{"invalid key", "/api/v1/weather/London", "wrong-key", http.StatusUnauthorized, "", "Invalid credentials"},
+		{"invalid query key", "/api/v1/weather/London?api_key=wrong-key", "", http.StatusUnauthorized, "", "Invalid credentials"},
+		{"exempt path", "/api/v1/health", "", http.StatusOK, "anonymous", ""},
+		{"exempt path ignores bad key", "/api/v1/health", "wrong-key", http.StatusOK, "anonymous", ""},
+		{"exemption is exact", "/api/v1/health/extra", "", http.StatusUnauthorized, "", "Authentication is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-API-Key", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d; body: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus == http.StatusOK {
+				if rec.Body.String() != tt.wantBody {
+					t.Errorf("consumer = %q, want %q", rec.Body, tt.wantBody)
+				}
+				return
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != `ApiKey realm="weather-api"` {
+				t.Errorf("WWW-Authenticate = %q", got)
+			}
+			var body models.ErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", body.Message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestAdminTokenMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consumer, ok := auth.ConsumerFromContext(r.Context())
+		if !ok || !consumer.HasScope("admin") {
+			t.Error("admin request without the admin token consumer")
+		}
+	})
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantStatus    int
+	}{
+		{"missing token", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"token prefix", "s3cret", "Bearer s3cre", http.StatusUnauthorized},
+		{"wrong scheme", "s3cret", "Basic s3cret", http.StatusUnauthorized},
+		{"right token", "s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin/quota", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			AdminTokenMiddleware(tt.token)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Bearer realm="admin"` {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// Without a token, as allowed on loopback, requests pass unauthenticated
+	rec := httptest.NewRecorder()
+	AdminTokenMiddleware("")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/quota", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status without a configured token = %d, want 200", rec.Code)
+	}
+}
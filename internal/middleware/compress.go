@@ -0,0 +1,295 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// supportedEncodings lists the content codings offered, in server preference
+// order, which breaks ties between codings the client weighs equally
+var supportedEncodings = []string{"zstd", "br", "gzip"}
+
+// compressibleTypes are the media types worth compressing; images, archives
+// and profiles are already compressed
+var compressibleTypes = map[string]bool{
+	"application/json":     true,
+	"application/geo+json": true,
+	"application/xml":      true,
+	"application/x-ndjson": true,
+	"text/csv":             true,
+	"text/event-stream":    true,
+	"text/html":            true,
+	"text/plain":           true,
+	"text/xml":             true,
+}
+
+// encoder is implemented by gzip.Writer, brotli.Writer and zstd.Encoder
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// encoderPools reuse encoders between responses, keyed by content coding
+var encoderPools = map[string]*sync.Pool{
+	"gzip": {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	// Quality 5 keeps brotli close to gzip's speed while still compressing better
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, 5)
+	}},
+	"zstd": {New: func() interface{} {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return enc
+	}},
+}
+
+// CompressionMiddleware compresses response bodies with zstd, brotli or
+// gzip, whichever the client's Accept-Encoding prefers. Bodies shorter than
+// minSize are sent as they are, except for streamed responses, which are
+// compressed from their first flush. When the handler panics, nothing
+// buffered is sent, so recovery further out can still answer 500.
+func CompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// WebSocket upgrades take over the connection
+			if r.Header.Get("Upgrade") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+			completed := false
+			defer func() {
+				if completed {
+					cw.close()
+				} else {
+					cw.abandon()
+				}
+			}()
+			next.ServeHTTP(cw, r)
+			completed = true
+		})
+	}
+}
+
+// negotiateEncoding picks the supported content coding with the highest
+// quality value in an Accept-Encoding header, or "" for identity
+func negotiateEncoding(header string) string {
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			// A malformed weight disables the coding rather than guessing
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || parsed < 0 || parsed > 1 {
+				parsed = 0
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range supportedEncodings {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// body is worth compressing: it reaches minSize, the handler flushes, or the
+// handler returns. Headers are sent once that decision is made.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	statusCode int
+	buf        []byte
+	started    bool
+	enc        encoder
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	// Informational responses go straight through; the final status waits
+	if code >= 100 && code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if cw.statusCode == 0 {
+		cw.statusCode = code
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.statusCode = http.StatusOK
+	}
+	if cw.started {
+		if cw.enc != nil {
+			return cw.enc.Write(b)
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+
+	if !cw.compressible() {
+		if err := cw.start(false); err != nil {
+			return 0, err
+		}
+		return cw.ResponseWriter.Write(b)
+	}
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// FlushError sends buffered data to the client, compressing streamed
+// responses such as server-sent events from the first flush
+func (cw *compressWriter) FlushError() error {
+	if !cw.started {
+		if cw.statusCode == 0 {
+			cw.statusCode = http.StatusOK
+		}
+		if err := cw.start(cw.compressible()); err != nil {
+			return err
+		}
+	}
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Flush implements http.Flusher
+func (cw *compressWriter) Flush() {
+	cw.FlushError()
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// compressible reports whether the response as described so far should be compressed
+func (cw *compressWriter) compressible() bool {
+	if cw.statusCode < 200 || cw.statusCode == http.StatusNoContent || cw.statusCode == http.StatusNotModified {
+		return false
+	}
+	header := cw.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && compressibleTypes[mediaType]
+}
+
+// start sends the headers, then any buffered body through the encoder when compressing
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	header := cw.Header()
+	// A compressed body differs byte for byte, so a strong ETag no longer
+	// holds; 304s are weakened too to match the compressed copy clients hold
+	if compress || cw.statusCode == http.StatusNotModified {
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+	if compress {
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		cw.enc = encoderPools[cw.encoding].Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// close sends a response still below minSize uncompressed, or finishes the
+// compressed stream and returns the encoder to its pool
+func (cw *compressWriter) close() {
+	if !cw.started {
+		if cw.statusCode == 0 {
+			// Nothing was written; net/http sends its default empty response
+			return
+		}
+		cw.start(false)
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+		cw.enc.Reset(nil)
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
+
+// abandon drops a response whose handler panicked. A response not yet
+// started is discarded; a started compressed stream is left unfinished so
+// the client sees it was cut short rather than a complete body.
+func (cw *compressWriter) abandon() {
+	cw.buf = nil
+	if cw.enc != nil {
+		cw.enc.Reset(nil)
+		encoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// conditionalHandler serves a body large enough to be compressed, with the
+// validators API handlers send
+func conditionalHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.SetCacheHeaders(w, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), time.Minute)
+		utils.WriteSuccessResponse(w, r, map[string]string{"description": strings.Repeat("overcast clouds ", 200)})
+	})
+}
+
+func request(handler http.Handler, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/weather/London", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCompressionConditionalRequests(t *testing.T) {
+	handler := CompressionMiddleware(1024)(conditionalHandler())
+	gzipped := http.Header{"Accept-Encoding": {"gzip"}}
+
+	first := request(handler, gzipped)
+	if first.Code != http.StatusOK || first.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("status %d, encoding %q; want a gzipped 200", first.Code, first.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(first.Body)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if body, err := io.ReadAll(zr); err != nil || !strings.Contains(string(body), "overcast clouds") {
+		t.Fatalf("decompressed body = %.40q, %v", body, err)
+	}
+
+	// The compressed body differs from the encoded one, so its tag is weak
+	weak := first.Header().Get("ETag")
+	if !strings.HasPrefix(weak, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak tag on a compressed response", weak)
+	}
+	strong := strings.TrimPrefix(weak, "W/")
+	if got := request(handler, nil).Header().Get("ETag"); got != strong {
+		t.Errorf("uncompressed ETag = %q, want %q", got, strong)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"weak If-None-Match", http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {weak}}, http.StatusNotModified},
+		{"strong If-None-Match", http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {strong}}, http.StatusNotModified},
+		{"weak tag without compression", http.Header{"If-None-Match": {weak}}, http.StatusNotModified},
+		{"If-Modified-Since", http.Header{"Accept-Encoding": {"gzip"}, "If-Modified-Since": {"Sun, 01 Mar 2026 12:00:00 GMT"}}, http.StatusNotModified},
+		{"changed tag", http.Header{"Accept-Encoding": {"gzip"}, "If-None-Match": {`W/"other"`}}, http.StatusOK},
+		{"modified since", http.Header{"Accept-Encoding": {"gzip"}, "If-Modified-Since": {"Sun, 01 Mar 2026 11:00:00 GMT"}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := request(handler, tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusNotModified {
+				return
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("304 has a %d byte body", rec.Body.Len())
+			}
+			if rec.Header().Get("Content-Encoding") != "" {
+				t.Errorf("304 has Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+			}
+			// 304s carry the tag of the representation the client holds
+			wantTag := strong
+			if tt.header.Get("Accept-Encoding") != "" {
+				wantTag = weak
+			}
+			if got := rec.Header().Get("ETag"); got != wantTag {
+				t.Errorf("304 ETag = %q, want %q", got, wantTag)
+			}
+			if rec.Header().Get("Last-Modified") == "" {
+				t.Error("304 has no Last-Modified")
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"gzip, zstd", "zstd"},
+		{"gzip;q=1, zstd;q=0.5", "gzip"},
+		{"*", "zstd"},
+		{"*;q=0.5, zstd;q=0", "br"},
+		{"*;q=0.5, zstd;q=0, br;q=0", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"gzip, br;q=0.9", "gzip"},
+		{"br;q=0.5, zstd;q=0.5", "zstd"},
+		{"gzip;q=0", ""},
+		{"gzip;q=abc", ""},
+		{"br, identity", "br"},
+		{"deflate, identity", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressionEncodings(t *testing.T) {
+	handler := CompressionMiddleware(1024)(conditionalHandler())
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for encoding, decode := range decoders {
+		t.Run(encoding, func(t *testing.T) {
+			// Repeat the request so pooled encoders are reused
+			for i := 0; i < 2; i++ {
+				rec := request(handler, http.Header{"Accept-Encoding": {encoding}})
+				if got := rec.Header().Get("Content-Encoding"); got != encoding {
+					t.Fatalf("Content-Encoding = %q, want %q", got, encoding)
+				}
+				r, err := decode(rec.Body)
+				if err != nil {
+					t.Fatalf("decoder: %v", err)
+				}
+				body, err := io.ReadAll(r)
+				if err != nil || !strings.Contains(string(body), "overcast clouds") {
+					t.Fatalf("decompressed body = %.40q, %v", body, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressionHandlerPanic(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"nothing written", ""},
+		{"partial body buffered", `{"city":"London",`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RecoveryMiddleware(CompressionMiddleware(1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+				panic("boom")
+			})))
+
+			rec := request(handler, http.Header{"Accept-Encoding": {"br"}})
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", rec.Code)
+			}
+			if rec.Header().Get("Content-Encoding") != "" {
+				t.Errorf("Content-Encoding = %q on the error response", rec.Header().Get("Content-Encoding"))
+			}
+			if body := rec.Body.String(); strings.Contains(body, "London") || !strings.Contains(body, "Internal Server Error") {
+				t.Errorf("body = %q, want only the error", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/metrics"
+	"github.com/gorilla/mux"
+)
+
+// routeLabelKey is the context key of the route label filled in by RecordRoute
+type routeLabelKey struct{}
+
+// routeLabel receives the matched route template from inside the router
+type routeLabel struct {
+	template string
+}
+
+// MetricsMiddleware records request counts, latency and in-flight requests.
+// Requests are labelled by their mux route template rather than the raw path
+// so per-city URLs do not create a new series each. It wraps the whole router
+// so requests no route matches, answered 404 or 405, are counted as
+// "unmatched"; the router must use RecordRoute to report matched templates.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		metrics.HTTPInFlight.Inc()
+		defer metrics.HTTPInFlight.Dec()
+
+		label := &routeLabel{template: "unmatched"}
+		wrapped := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(wrapped, r.WithContext(context.WithValue(r.Context(), routeLabelKey{}, label)))
+
+		labels := []string{label.template, r.Method, strconv.Itoa(wrapped.status())}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// RecordRoute reports the template of the matched route to MetricsMiddleware.
+// mux only knows the route inside the router, so this is installed with Use.
+func RecordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if label, ok := r.Context().Value(routeLabelKey{}).(*routeLabel); ok {
+			label.template = routeTemplate(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeTemplate returns the path template of the matched route
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return "unmatched"
+	}
+	return template
+}
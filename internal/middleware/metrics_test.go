@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/metrics"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsRouteLabels(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(RecordRoute)
+	r.HandleFunc("/api/v1/weather/{city}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	r.HandleFunc("/api/v1/teapot", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := MetricsMiddleware(r)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		route  string
+		status string
+	}{
+		{"template instead of path", http.MethodGet, "/api/v1/weather/London", "/api/v1/weather/{city}", "200"},
+		{"handler status", http.MethodGet, "/api/v1/teapot", "/api/v1/teapot", "418"},
+		{"no route", http.MethodGet, "/api/v1/nothing/here", "unmatched", "404"},
+		{"wrong method", http.MethodPost, "/api/v1/weather/London", "unmatched", "405"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.HTTPRequests.WithLabelValues(tt.route, tt.method, tt.status)
+			before := testutil.ToFloat64(counter)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests{route=%q,method=%q,status=%q} grew by %v, want 1", tt.route, tt.method, tt.status, got)
+			}
+		})
+	}
+
+	// Raw paths must never become label values
+	if got := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/api/v1/weather/London", http.MethodGet, "200")); got != 0 {
+		t.Errorf("raw path series = %v, want 0", got)
+	}
+}
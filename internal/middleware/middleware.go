@@ -1,11 +1,60 @@
 package middleware
 
 import (
-	"log"
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/logging"
+	"github.com/ANAS727189/weather-project/internal/utils"
 )
 
+// RequestIDHeader carries the request ID between clients, proxies and this service
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs kept in logs
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware reuses a well-formed X-Request-ID from the client or
+// generates one, echoes it on the response and attaches it to the request context
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII without spaces
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // LoggingMiddleware logs all incoming requests
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -16,34 +65,79 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		next.ServeHTTP(wrapped, r)
 
-		log.Printf(
-			"[%s] %s %s %d %v",
-			r.Method,
-			r.RequestURI,
-			r.RemoteAddr,
-			wrapped.statusCode,
-			time.Since(start),
+		slog.InfoContext(r.Context(), "request",
+			"method", r.Method,
+			"uri", redactURI(r),
+			"remote_addr", r.RemoteAddr,
+			"status", wrapped.status(),
+			"bytes", wrapped.bytes,
+			"duration", time.Since(start),
 		)
 	})
 }
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// redactURI returns the request URI with credential query parameters masked
+func redactURI(r *http.Request) string {
+	query := r.URL.Query()
+	if !utils.RedactQuery(query) {
+		return r.RequestURI
+	}
+	return r.URL.Path + "?" + query.Encode()
+}
+
+// responseWriter wraps http.ResponseWriter to capture status code and body size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
+	if rw.statusCode == 0 {
+		rw.statusCode = code
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Write records the implicit 200 status when the handler never called WriteHeader
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.statusCode = http.StatusOK
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// status returns the response status, which is 200 when nothing was written
+func (rw *responseWriter) status() int {
+	if rw.statusCode == 0 {
+		return http.StatusOK
+	}
+	return rw.statusCode
+}
+
+// Hijack lets WebSocket upgrades take over the underlying connection
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap exposes the underlying ResponseWriter to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RecoveryMiddleware recovers from panics and returns a 500 error
 func RecoveryMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				slog.ErrorContext(r.Context(), "panic recovered", "error", err, "path", r.URL.Path)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -51,40 +145,67 @@ func RecoveryMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// CORSMiddleware sets CORS headers
-func CORSMiddleware(allowedOrigins, allowedMethods, allowedHeaders []string, allowCredentials bool) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
+// CORS sets CORS headers from settings that can be replaced at runtime with Update
+type CORS struct {
+	settings atomic.Pointer[config.CORSConfig]
+}
 
-			// Check if origin is allowed
-			for _, allowedOrigin := range allowedOrigins {
-				if allowedOrigin == "*" || allowedOrigin == origin {
-					w.Header().Set("Access-Control-Allow-Origin", origin)
-					break
-				}
-			}
+// NewCORS creates CORS middleware for the given settings
+func NewCORS(cfg config.CORSConfig) *CORS {
+	c := &CORS{}
+	c.Update(cfg)
+	return c
+}
 
-			// Set other CORS headers
-			if len(allowedMethods) > 0 {
-				w.Header().Set("Access-Control-Allow-Methods", joinStrings(allowedMethods, ", "))
-			}
-			if len(allowedHeaders) > 0 {
-				w.Header().Set("Access-Control-Allow-Headers", joinStrings(allowedHeaders, ", "))
-			}
-			if allowCredentials {
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-			}
+// Update swaps in new CORS settings for subsequent requests
+func (c *CORS) Update(cfg config.CORSConfig) {
+	c.settings.Store(&cfg)
+}
 
-			// Handle preflight requests
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
+// AllowsOrigin reports whether the current settings allow requests from origin
+func (c *CORS) AllowsOrigin(origin string) bool {
+	return allowsOrigin(c.settings.Load(), origin)
+}
 
-			next.ServeHTTP(w, r)
-		})
+func allowsOrigin(cfg *config.CORSConfig, origin string) bool {
+	for _, allowedOrigin := range cfg.AllowedOrigins {
+		if allowedOrigin == "*" || allowedOrigin == origin {
+			return true
+		}
 	}
+	return false
+}
+
+// Middleware sets CORS headers and answers preflight requests
+func (c *CORS) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := c.settings.Load()
+		origin := r.Header.Get("Origin")
+
+		// Check if origin is allowed
+		if allowsOrigin(cfg, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		// Set other CORS headers
+		if len(cfg.AllowedMethods) > 0 {
+			w.Header().Set("Access-Control-Allow-Methods", joinStrings(cfg.AllowedMethods, ", "))
+		}
+		if len(cfg.AllowedHeaders) > 0 {
+			w.Header().Set("Access-Control-Allow-Headers", joinStrings(cfg.AllowedHeaders, ", "))
+		}
+		if cfg.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		// Handle preflight requests
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
 
 func joinStrings(strs []string, sep string) string {
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/logging"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"uuid", "3f2b8c1e-9d4a-4c6b-8e2f-1a7d5c9b0e34", true},
+		{"printable punctuation", "edge:42/abc_DEF.~", true},
+		{"longest allowed", strings.Repeat("a", maxRequestIDLength), true},
+		{"empty", "", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"space", "abc def", false},
+		{"newline", "abc\ndef", false},
+		{"control character", "abc\x1bdef", false},
+		{"delete", "abc\x7f", false},
+		{"non-ASCII", "réquest", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validRequestID(tt.id); got != tt.want {
+				t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestIDFromContext(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"accepts client ID", "client-supplied-42", true},
+		{"generates when missing", "", false},
+		{"replaces ID with spaces", "two words", false},
+		{"replaces oversized ID", strings.Repeat("x", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			echoed := rec.Header().Get(RequestIDHeader)
+			if echoed != seen {
+				t.Errorf("response ID %q, context ID %q", echoed, seen)
+			}
+			if tt.keep {
+				if echoed != tt.incoming {
+					t.Errorf("response ID = %q, want %q", echoed, tt.incoming)
+				}
+				return
+			}
+			if echoed == tt.incoming || len(echoed) != 32 || !validRequestID(echoed) {
+				t.Errorf("response ID = %q, want a generated ID", echoed)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareStatus(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  int
+		bytes   int
+	}{
+		{"implicit 200 on write", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) }, http.StatusOK, 5},
+		{"implicit 200 without body", func(w http.ResponseWriter, r *http.Request) {}, http.StatusOK, 0},
+		{"explicit status", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("gone"))
+		}, http.StatusNotFound, 4},
+		{"first status wins", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			w.WriteHeader(http.StatusInternalServerError)
+		}, http.StatusAccepted, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			LoggingMiddleware(tt.handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/weather/London", nil))
+
+			var entry struct {
+				Msg    string `json:"msg"`
+				Status int    `json:"status"`
+				Bytes  int    `json:"bytes"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("decode log line %q: %v", buf.String(), err)
+			}
+			if entry.Msg != "request" || entry.Status != tt.status || entry.Bytes != tt.bytes {
+				t.Errorf("logged %+v, want status %d and %d bytes", entry, tt.status, tt.bytes)
+			}
+		})
+	}
+}
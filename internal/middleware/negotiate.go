@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ANAS727189/weather-project/internal/utils"
+)
+
+// ContentNegotiation selects the response format from ?format= or the Accept
+// header among formats, listed in order of preference, and answers 406 when
+// none is acceptable. The format is attached to the request context, where
+// the utils response writers find it.
+func ContentNegotiation(formats ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			format, err := utils.NegotiateFormat(r, formats)
+			if err != nil {
+				utils.WriteErrorResponse(w, r, err.Error(), http.StatusNotAcceptable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(utils.WithFormat(r.Context(), format)))
+		})
+	}
+}
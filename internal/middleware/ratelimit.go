@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/ratelimit"
+	"github.com/ANAS727189/weather-project/internal/utils"
+)
+
+// RateLimiter applies a token bucket per API consumer, or per client IP for
+// anonymous requests, and reports quota state in X-RateLimit-* headers.
+// Requests to exempt paths are not limited. Tiers and trusted proxies can be
+// replaced at runtime with Update.
+type RateLimiter struct {
+	limiter  *ratelimit.Limiter
+	exempt   map[string]bool
+	settings atomic.Pointer[rateLimitSettings]
+}
+
+type rateLimitSettings struct {
+	cfg     config.RateLimitConfig
+	trusted []*net.IPNet
+}
+
+// NewRateLimiter creates rate limiting middleware backed by limiter
+func NewRateLimiter(limiter *ratelimit.Limiter, cfg config.RateLimitConfig, exemptPaths []string) (*RateLimiter, error) {
+	rl := &RateLimiter{
+		limiter: limiter,
+		exempt:  make(map[string]bool, len(exemptPaths)),
+	}
+	for _, path := range exemptPaths {
+		rl.exempt[path] = true
+	}
+	if err := rl.Update(cfg); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
+// Update swaps in new tiers and trusted proxies; existing buckets keep their tokens
+func (rl *RateLimiter) Update(cfg config.RateLimitConfig) error {
+	trusted, err := parseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		return err
+	}
+	rl.settings.Store(&rateLimitSettings{cfg: cfg, trusted: trusted})
+	return nil
+}
+
+// Middleware limits requests passing through next
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		settings := rl.settings.Load()
+		cfg := settings.cfg
+
+		var key, tierName string
+		if consumer, ok := auth.ConsumerFromContext(r.Context()); ok {
+			// Keys and tokens get separate buckets even when an ID matches a subject
+			key = consumer.Kind + ":" + consumer.ID
+			tierName = consumer.Tier
+			if _, ok := cfg.Tiers[tierName]; !ok {
+				tierName = cfg.DefaultTier
+			}
+		} else {
+			key = "ip:" + clientIP(r, settings.trusted)
+			tierName = cfg.AnonymousTier
+		}
+		tier := cfg.Tiers[tierName]
+
+		result := rl.limiter.Allow(key, ratelimit.Tier{
+			RequestsPerMinute: tier.RequestsPerMinute,
+			Burst:             tier.Burst,
+		})
+
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			slog.InfoContext(r.Context(), "rate limit exceeded", "client", key, "tier", tierName)
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter), http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// LimitAuthFailures throttles credential guessing. It runs before
+// authentication, which Middleware follows so it can pick the consumer's
+// bucket. Every 401 answered by next takes a token from a bucket for the client
+// IP sized by the anonymous tier, and once it is empty the IP is refused with
+// 429 before its credentials are checked again.
+func (rl *RateLimiter) LimitAuthFailures(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rl.exempt[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		settings := rl.settings.Load()
+		anonymous := settings.cfg.Tiers[settings.cfg.AnonymousTier]
+		tier := ratelimit.Tier{RequestsPerMinute: anonymous.RequestsPerMinute, Burst: anonymous.Burst}
+		key := "authfail:" + clientIP(r, settings.trusted)
+
+		if result := rl.limiter.Peek(key, tier); !result.Allowed {
+			retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			slog.InfoContext(r.Context(), "too many failed authentication attempts", "client", key)
+			utils.WriteErrorResponse(w, r, fmt.Sprintf("Too many failed authentication attempts, retry in %d seconds", retryAfter), http.StatusTooManyRequests)
+			return
+		}
+
+		wrapped := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(wrapped, r)
+		if wrapped.status() == http.StatusUnauthorized {
+			rl.limiter.Allow(key, tier)
+		}
+	})
+}
+
+// parseTrustedProxies parses proxy IPs and CIDR ranges
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %v", proxy, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// clientIP returns the originating client address. X-Forwarded-For is only
+// honoured when the request arrives from a trusted proxy, and is walked from
+// the right so clients cannot spoof entries added by our own proxies.
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+
+	if !isTrusted(remote, trusted) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i] == "" {
+			continue
+		}
+		if !isTrusted(hops[i], trusted) {
+			return hops[i]
+		}
+	}
+	return remote
+}
+
+func isTrusted(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/ratelimit"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"})
+	if err != nil {
+		t.Fatalf("parseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "203.0.113.7"},
+		{"untrusted remote ignores header", "203.0.113.7:1234", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"spoofed leftmost entry", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.1"}, "198.51.100.1"},
+		{"proxy chain", "10.0.0.1:1234", []string{"198.51.100.1, 192.168.1.1, 10.0.0.2"}, "198.51.100.1"},
+		{"spoofed trusted looking entry", "10.0.0.1:1234", []string{"10.9.9.9, 198.51.100.1, 10.0.0.2"}, "198.51.100.1"},
+		{"multiple headers", "10.0.0.1:1234", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		{"blank hops", "10.0.0.1:1234", []string{"198.51.100.1, , "}, "198.51.100.1"},
+		{"garbage hop", "10.0.0.1:1234", []string{"198.51.100.1, not-an-ip"}, "not-an-ip"},
+		{"only trusted hops", "10.0.0.1:1234", []string{"10.0.0.3"}, "10.0.0.1"},
+		{"trusted without header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"ipv6", "[fd00::1]:1234", []string{"2001:db8::1"}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			for _, value := range tt.xff {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(req, trusted); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsGarbage(t *testing.T) {
+	if _, err := parseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+	if _, err := parseTrustedProxies([]string{"proxy.internal"}); err == nil {
+		t.Error("expected an error for a host name")
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	rl, err := NewRateLimiter(ratelimit.NewLimiter(100, time.Hour), config.RateLimitConfig{
+		DefaultTier:   "standard",
+		AnonymousTier: "anonymous",
+		Tiers: map[string]config.RateLimitTier{
+			"anonymous": {RequestsPerMinute: 1, Burst: 1},
+			"standard":  {RequestsPerMinute: 1, Burst: 2},
+		},
+	}, []string{"/api/v1/health"})
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(path, remote string, consumer *auth.Consumer) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		if consumer != nil {
+			req = req.WithContext(auth.WithConsumer(req.Context(), consumer))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Anonymous clients share the anonymous tier per IP
+	if rec := serve("/api/v1/weather/London", "203.0.113.7:1", nil); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("first anonymous request = %d, limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	rec := serve("/api/v1/weather/London", "203.0.113.7:2", nil)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second anonymous request = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" || rec.Header().Get("X-RateLimit-Remaining") != "0" || rec.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Errorf("headers = %v", rec.Header())
+	}
+	if rec := serve("/api/v1/weather/London", "203.0.113.8:1", nil); rec.Code != http.StatusOK {
+		t.Errorf("other IP = %d, want 200", rec.Code)
+	}
+	if rec := serve("/api/v1/health", "203.0.113.7:3", nil); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("exempt path = %d with headers %v", rec.Code, rec.Header())
+	}
+
+	// Consumers use their tier, falling back to the default, and API keys and
+	// token subjects with the same ID do not share a bucket
+	key := &auth.Consumer{ID: "portal", Kind: auth.KindAPIKey, Tier: "unknown"}
+	token := &auth.Consumer{ID: "portal", Kind: auth.KindJWT, Tier: "standard"}
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if rec := serve("/api/v1/weather/London", "203.0.113.7:4", key); rec.Code != want {
+			t.Fatalf("API key request %d = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if rec := serve("/api/v1/weather/London", "203.0.113.7:5", token); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("token request = %d with %s remaining, want a separate bucket", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestLimitAuthFailures(t *testing.T) {
+	rl, err := NewRateLimiter(ratelimit.NewLimiter(100, time.Hour), config.RateLimitConfig{
+		DefaultTier:   "standard",
+		AnonymousTier: "anonymous",
+		Tiers: map[string]config.RateLimitTier{
+			"anonymous": {RequestsPerMinute: 1, Burst: 2},
+			"standard":  {RequestsPerMinute: 60, Burst: 60},
+		},
+	}, []string{"/api/v1/health"})
+	if err != nil {
+		t.Fatalf("NewRateLimiter: %v", err)
+	}
+	// Stands in for AuthMiddleware: only the key "good" is accepted
+	var checked int
+	handler := rl.LimitAuthFailures(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checked++
+		if r.Header.Get("X-API-Key") != "good" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	serve := func(path, remote, key string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Successful requests never use up the failure bucket
+	for i := 0; i < 5; i++ {
+		if code := serve("/api/v1/weather/London", "203.0.113.7:1", "good"); code != http.StatusOK {
+			t.Fatalf("valid request %d = %d, want 200", i+1, code)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		remote string
+		key    string
+		want   int
+	}{
+		{"first guess", "/api/v1/weather/London", "203.0.113.7:2", "guess-1", http.StatusUnauthorized},
+		{"second guess", "/api/v1/weather/London", "203.0.113.7:3", "guess-2", http.StatusUnauthorized},
+		{"third guess is refused unchecked", "/api/v1/weather/London", "203.0.113.7:4", "guess-3", http.StatusTooManyRequests},
+		{"blocked IP with a valid key", "/api/v1/weather/London", "203.0.113.7:5", "good", http.StatusTooManyRequests},
+		{"other IP", "/api/v1/weather/London", "203.0.113.8:1", "guess-4", http.StatusUnauthorized},
+		{"exempt path passes a blocked IP", "/api/v1/health", "203.0.113.7:6", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := checked
+			if code := serve(tt.path, tt.remote, tt.key); code != tt.want {
+				t.Fatalf("status = %d, want %d", code, tt.want)
+			}
+			if tt.want == http.StatusTooManyRequests && checked != before {
+				t.Error("credentials were checked for a refused client")
+			}
+		})
+	}
+}
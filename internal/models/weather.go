@@ -1,12 +1,22 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 // WeatherData represents the current weather response from OpenWeatherMap API
 type WeatherData struct {
 	Name    string `json:"name"`
 	Country string `json:"country"`
-	Main    struct {
+	Coord   struct {
+		Lon float64 `json:"lon"`
+		Lat float64 `json:"lat"`
+	} `json:"coord"`
+	Main struct {
 		Temp      float64 `json:"temp"`
 		FeelsLike float64 `json:"feels_like"`
 		TempMin   float64 `json:"temp_min"`
@@ -34,6 +44,11 @@ type WeatherData struct {
 	Visibility int   `json:"visibility"`
 	Timezone   int   `json:"timezone"`
 	Dt         int64 `json:"dt"`
+
+	// FetchedAt and ExpiresAt record when the data was fetched upstream and
+	// when its cached copy expires; they are not part of the payload
+	FetchedAt time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
 }
 
 // ForecastData represents the forecast response from OpenWeatherMap API
@@ -43,6 +58,16 @@ type ForecastData struct {
 		Name    string `json:"name"`
 		Country string `json:"country"`
 	} `json:"city"`
+
+	// FetchedAt and ExpiresAt record when the data was fetched upstream and
+	// when its cached copy expires; they are not part of the payload
+	FetchedAt time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Records returns the forecast slots, the rows of tabular output formats
+func (f *ForecastData) Records() interface{} {
+	return f.List
 }
 
 // ForecastItem represents a single forecast item
@@ -71,6 +96,73 @@ type ForecastItem struct {
 	DtTxt string `json:"dt_txt"`
 }
 
+// AlertsData represents the weather alerts in force at a location, as
+// reported by the OpenWeatherMap One Call API
+type AlertsData struct {
+	Lat      float64        `json:"lat"`
+	Lon      float64        `json:"lon"`
+	Timezone string         `json:"timezone"`
+	Alerts   []WeatherAlert `json:"alerts"`
+
+	// FetchedAt and ExpiresAt record when the data was fetched upstream and
+	// when its cached copy expires; they are not part of the payload
+	FetchedAt time.Time `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// Records returns the alerts without their areas, which do not fit in rows,
+// as the rows of tabular output formats
+func (a *AlertsData) Records() interface{} {
+	rows := make([]WeatherAlert, len(a.Alerts))
+	for i, alert := range a.Alerts {
+		alert.Polygons = nil
+		rows[i] = alert
+	}
+	return rows
+}
+
+// GeoJSON returns the alerts as a feature collection with one feature per
+// alert: a Polygon or MultiPolygon for alerts with an area, otherwise a Point
+// at the requested location
+func (a *AlertsData) GeoJSON() interface{} {
+	features := make([]Feature, 0, len(a.Alerts))
+	for _, alert := range a.Alerts {
+		var polygons [][][][]float64
+		for _, rings := range alert.Polygons {
+			if polygon, ok := normalizePolygon(rings); ok {
+				polygons = append(polygons, polygon)
+			}
+		}
+		alert.Polygons = nil
+
+		switch len(polygons) {
+		case 0:
+			features = append(features, NewPointFeature(a.Lon, a.Lat, alert))
+		case 1:
+			features = append(features, NewPolygonFeature(polygons[0], alert))
+		default:
+			features = append(features, NewMultiPolygonFeature(polygons, alert))
+		}
+	}
+	return NewFeatureCollection(features...)
+}
+
+// WeatherAlert represents a single warning issued by a national weather service
+type WeatherAlert struct {
+	SenderName  string   `json:"sender_name"`
+	Event       string   `json:"event"`
+	Start       int64    `json:"start"`
+	End         int64    `json:"end"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+
+	// Polygons is the area the alert covers, as GeoJSON polygon coordinates
+	// (linear rings of [longitude, latitude] positions, exterior ring first).
+	// OpenWeatherMap does not report areas, so it is only set when the
+	// configured alerts endpoint supplies them.
+	Polygons [][][][]float64 `json:"polygons,omitempty"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -80,9 +172,374 @@ type ErrorResponse struct {
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
+	Status     string                 `json:"status"`
+	Timestamp  time.Time              `json:"timestamp"`
+	Version    string                 `json:"version"`
+	Uptime     string                 `json:"uptime"`
+	Service    string                 `json:"service"`
+	Commit     string                 `json:"commit"`
+	BuildTime  string                 `json:"build_time"`
+	Components map[string]interface{} `json:"components,omitempty"`
+}
+
+// LivenessResponse represents the liveness probe response
+type LivenessResponse struct {
+	Status  string `json:"status"`
+	Version string `json:"version"`
+	Uptime  string `json:"uptime"`
+}
+
+// ReadinessResponse represents the readiness probe response
+type ReadinessResponse struct {
+	Status    string                 `json:"status"`
+	Timestamp time.Time              `json:"timestamp"`
+	Version   string                 `json:"version"`
+	Commit    string                 `json:"commit"`
+	BuildTime string                 `json:"build_time"`
+	Checks    map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult reports the outcome of a single readiness check
+type CheckResult struct {
 	Status    string    `json:"status"`
-	Timestamp time.Time `json:"timestamp"`
-	Version   string    `json:"version"`
-	Uptime    string    `json:"uptime"`
-	Service   string    `json:"service"`
+	Latency   string    `json:"latency"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Location identifies a place either by city name or by coordinates
+type Location struct {
+	City     string
+	Lat      float64
+	Lon      float64
+	ByCoords bool
+}
+
+// CityLocation returns a location for the given city name
+func CityLocation(city string) Location {
+	return Location{City: strings.ToLower(strings.TrimSpace(city))}
+}
+
+// CoordLocation returns a location for the given coordinates
+func CoordLocation(lat, lon float64) Location {
+	return Location{Lat: lat, Lon: lon, ByCoords: true}
+}
+
+// Key returns a canonical identifier for the location
+func (l Location) Key() string {
+	if l.ByCoords {
+		return fmt.Sprintf("coord:%.4f,%.4f", l.Lat, l.Lon)
+	}
+	return "city:" + l.City
+}
+
+// String returns a human readable form of the location
+func (l Location) String() string {
+	if l.ByCoords {
+		return fmt.Sprintf("%.4f,%.4f", l.Lat, l.Lon)
+	}
+	return l.City
+}
+
+// MarshalJSON encodes the location as either a city or a coordinate pair
+func (l Location) MarshalJSON() ([]byte, error) {
+	if l.ByCoords {
+		return json.Marshal(struct {
+			Lat float64 `json:"lat"`
+			Lon float64 `json:"lon"`
+		}{l.Lat, l.Lon})
+	}
+	return json.Marshal(struct {
+		City string `json:"city"`
+	}{l.City})
+}
+
+// SocketRequest represents a subscription message sent by WebSocket clients
+type SocketRequest struct {
+	Type   string   `json:"type"`
+	City   string   `json:"city,omitempty"`
+	Lat    *float64 `json:"lat,omitempty"`
+	Lon    *float64 `json:"lon,omitempty"`
+	Topics []string `json:"topics,omitempty"`
+}
+
+// SocketMessage represents a frame sent to WebSocket clients
+type SocketMessage struct {
+	Type     string      `json:"type"`
+	ID       uint64      `json:"id,omitempty"`
+	Location *Location   `json:"location,omitempty"`
+	Topics   []string    `json:"topics,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Dropped  uint64      `json:"dropped,omitempty"`
+	Message  string      `json:"message,omitempty"`
+}
+
+// Observation represents a normalized weather observation recorded for a location
+type Observation struct {
+	Location    string    `json:"location"`
+	Name        string    `json:"name"`
+	Country     string    `json:"country"`
+	Lat         float64   `json:"lat"`
+	Lon         float64   `json:"lon"`
+	ObservedAt  time.Time `json:"observed_at"`
+	RecordedAt  time.Time `json:"recorded_at"`
+	Temp        float64   `json:"temp"`
+	FeelsLike   float64   `json:"feels_like"`
+	Humidity    int       `json:"humidity"`
+	Pressure    int       `json:"pressure"`
+	WindSpeed   float64   `json:"wind_speed"`
+	WindDeg     int       `json:"wind_deg"`
+	Clouds      int       `json:"clouds"`
+	Visibility  int       `json:"visibility"`
+	Condition   string    `json:"condition"`
+	Description string    `json:"description"`
+}
+
+// NewObservation normalizes current weather data into an observation for a location
+func NewObservation(loc Location, data *WeatherData, recordedAt time.Time) Observation {
+	obs := Observation{
+		Location:   loc.Key(),
+		Name:       data.Name,
+		Country:    data.Sys.Country,
+		Lat:        data.Coord.Lat,
+		Lon:        data.Coord.Lon,
+		ObservedAt: time.Unix(data.Dt, 0).UTC(),
+		RecordedAt: recordedAt.UTC(),
+		Temp:       data.Main.Temp,
+		FeelsLike:  data.Main.FeelsLike,
+		Humidity:   data.Main.Humidity,
+		Pressure:   data.Main.Pressure,
+		WindSpeed:  data.Wind.Speed,
+		WindDeg:    data.Wind.Deg,
+		Clouds:     data.Clouds.All,
+		Visibility: data.Visibility,
+	}
+	if data.Dt == 0 {
+		obs.ObservedAt = obs.RecordedAt
+	}
+	if len(data.Weather) > 0 {
+		obs.Condition = data.Weather[0].Main
+		obs.Description = data.Weather[0].Description
+	}
+	return obs
+}
+
+// HistoryResponse represents time-bucketed observation history for a location
+type HistoryResponse struct {
+	Location   string          `json:"location"`
+	From       time.Time       `json:"from"`
+	To         time.Time       `json:"to"`
+	Interval   string          `json:"interval"`
+	Buckets    []HistoryBucket `json:"buckets"`
+	Pagination Pagination      `json:"pagination"`
+}
+
+// Records returns the buckets, the rows of tabular output formats
+func (h HistoryResponse) Records() interface{} {
+	return h.Buckets
+}
+
+// HistoryBucket represents aggregated observations within one time interval
+type HistoryBucket struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Count       int       `json:"count"`
+	Temperature Aggregate `json:"temperature"`
+	Humidity    Aggregate `json:"humidity"`
+	Pressure    Aggregate `json:"pressure"`
+	WindSpeed   Aggregate `json:"wind_speed"`
+
+	// LastObservedAt is the time of the most recent observation in the bucket
+	LastObservedAt time.Time `json:"-"`
+}
+
+// Aggregate represents the minimum, maximum and mean of a measurement
+type Aggregate struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// Pagination describes the page of results returned
+type Pagination struct {
+	Page       int `json:"page"`
+	PageSize   int `json:"page_size"`
+	TotalItems int `json:"total_items"`
+	TotalPages int `json:"total_pages"`
+}
+
+// CacheStats describes the contents of the response cache
+type CacheStats struct {
+	Entries        int            `json:"entries"`
+	Fresh          int            `json:"fresh"`
+	Stale          int            `json:"stale"`
+	ByKind         map[string]int `json:"by_kind"`
+	EstimatedBytes int            `json:"estimated_bytes"`
+}
+
+// CacheEntry describes a single cached response
+type CacheEntry struct {
+	Key        string    `json:"key"`
+	Kind       string    `json:"kind"`
+	Location   string    `json:"location"`
+	StoredAt   time.Time `json:"stored_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	AgeSeconds int       `json:"age_seconds"`
+	TTLSeconds int       `json:"ttl_seconds"`
+	Stale      bool      `json:"stale"`
+	SizeBytes  int       `json:"size_bytes"`
+}
+
+// CacheEntriesResponse lists cached responses
+type CacheEntriesResponse struct {
+	Entries []CacheEntry `json:"entries"`
+	Count   int          `json:"count"`
+}
+
+// CachePrewarmRequest lists the cities whose weather and forecast should be cached
+type CachePrewarmRequest struct {
+	Cities []string `json:"cities"`
+}
+
+// CachePrewarmResult reports the outcome of pre-warming one city
+type CachePrewarmResult struct {
+	City     string `json:"city"`
+	Weather  string `json:"weather"`
+	Forecast string `json:"forecast"`
+}
+
+// CachePurgeResponse reports how many cache entries were removed
+type CachePurgeResponse struct {
+	Purged int `json:"purged"`
+}
+
+// FeatureCollection is a GeoJSON (RFC 7946) feature collection
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// Feature is a GeoJSON feature
+type Feature struct {
+	Type       string      `json:"type"`
+	Geometry   Geometry    `json:"geometry"`
+	Properties interface{} `json:"properties"`
+}
+
+// Geometry is a GeoJSON geometry; positions are [longitude, latitude]
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// NewFeatureCollection creates a feature collection holding features
+func NewFeatureCollection(features ...Feature) FeatureCollection {
+	return FeatureCollection{Type: "FeatureCollection", Features: append([]Feature{}, features...)}
+}
+
+// NewPointFeature creates a feature with a Point geometry at lon, lat
+func NewPointFeature(lon, lat float64, properties interface{}) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Point", Coordinates: []float64{lon, lat}},
+		Properties: properties,
+	}
+}
+
+// NewPolygonFeature creates a feature with a Polygon geometry; rings must be
+// closed, with the exterior ring counterclockwise and holes clockwise
+func NewPolygonFeature(rings [][][]float64, properties interface{}) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Polygon", Coordinates: rings},
+		Properties: properties,
+	}
+}
+
+// NewMultiPolygonFeature creates a feature with a MultiPolygon geometry made
+// of polygons laid out as for NewPolygonFeature
+func NewMultiPolygonFeature(polygons [][][][]float64, properties interface{}) Feature {
+	return Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "MultiPolygon", Coordinates: polygons},
+		Properties: properties,
+	}
+}
+
+// normalizePolygon closes the rings of a polygon and winds them as RFC 7946
+// requires: the exterior ring counterclockwise and holes clockwise. Rings with
+// fewer than three distinct positions are dropped, and so is the polygon when
+// its exterior ring is.
+func normalizePolygon(rings [][][]float64) ([][][]float64, bool) {
+	var polygon [][][]float64
+	for i, ring := range rings {
+		if len(ring) > 0 && samePosition(ring[0], ring[len(ring)-1]) {
+			ring = ring[:len(ring)-1]
+		}
+		if len(ring) < 3 {
+			if i == 0 {
+				return nil, false
+			}
+			continue
+		}
+		closed := make([][]float64, 0, len(ring)+1)
+		closed = append(append(closed, ring...), ring[0])
+		if counterclockwise := ringArea(closed) > 0; counterclockwise != (i == 0) {
+			slices.Reverse(closed)
+		}
+		polygon = append(polygon, closed)
+	}
+	return polygon, len(polygon) > 0
+}
+
+// ringArea returns twice the signed area of a closed ring, positive when it
+// runs counterclockwise
+func ringArea(ring [][]float64) float64 {
+	var area float64
+	for i := 0; i+1 < len(ring); i++ {
+		area += ring[i][0]*ring[i+1][1] - ring[i+1][0]*ring[i][1]
+	}
+	return area
+}
+
+func samePosition(a, b []float64) bool {
+	return len(a) >= 2 && len(b) >= 2 && a[0] == b[0] && a[1] == b[1]
+}
+
+// GeoJSON returns current weather as a feature collection with one Point at
+// the reported coordinates, with the normalized observation as its properties
+func (w *WeatherData) GeoJSON() interface{} {
+	obs := NewObservation(CityLocation(w.Name), w, w.FetchedAt)
+	return NewFeatureCollection(NewPointFeature(w.Coord.Lon, w.Coord.Lat, obs))
+}
+
+// BatchWeatherResponse holds current weather for several cities
+type BatchWeatherResponse struct {
+	Results []BatchWeatherResult `json:"results"`
+	Count   int                  `json:"count"`
+}
+
+// BatchWeatherResult is the current weather of one city in a batch, or the
+// reason it could not be fetched
+type BatchWeatherResult struct {
+	City    string       `json:"city"`
+	Weather *Observation `json:"weather,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// Records returns one result per city, the rows of tabular output formats
+func (b *BatchWeatherResponse) Records() interface{} {
+	return b.Results
+}
+
+// GeoJSON returns the batch as a feature collection with one Point per city
+// whose weather was fetched; cities that failed have no position and are left out
+func (b *BatchWeatherResponse) GeoJSON() interface{} {
+	features := make([]Feature, 0, len(b.Results))
+	for _, result := range b.Results {
+		if result.Weather != nil {
+			features = append(features, NewPointFeature(result.Weather.Lon, result.Weather.Lat, result.Weather))
+		}
+	}
+	return NewFeatureCollection(features...)
 }
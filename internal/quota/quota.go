@@ -0,0 +1,155 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when no upstream calls remain in the current window
+var ErrBudgetExhausted = errors.New("upstream request budget exhausted")
+
+// Priority orders upstream calls when the budget runs low
+type Priority int
+
+const (
+	// PriorityUser is for calls made on behalf of a client request
+	PriorityUser Priority = iota
+	// PriorityBackground is for cache refreshes, streams and the collector
+	PriorityBackground
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx whose upstream calls use the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority attached to ctx, defaulting to PriorityUser
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityUser
+}
+
+// Status reports budget usage for the current windows
+type Status struct {
+	MinuteLimit        int       `json:"minute_limit"`
+	MinuteUsed         int       `json:"minute_used"`
+	MinuteRemaining    int       `json:"minute_remaining"`
+	MinuteResetsAt     time.Time `json:"minute_resets_at"`
+	DayLimit           int       `json:"day_limit"`
+	DayUsed            int       `json:"day_used"`
+	DayRemaining       int       `json:"day_remaining"`
+	DayResetsAt        time.Time `json:"day_resets_at"`
+	BackgroundReserve  int       `json:"background_reserve_percent"`
+	RejectedUser       uint64    `json:"rejected_user"`
+	RejectedBackground uint64    `json:"rejected_background"`
+}
+
+// Budget tracks upstream calls against per-minute and per-day limits using
+// fixed windows aligned to the UTC minute and day. Background calls may not
+// spend the last reserve percent of either window so user requests keep
+// working when the budget runs low.
+type Budget struct {
+	perMinute int
+	perDay    int
+	reserve   int
+	now       func() time.Time
+
+	mu                 sync.Mutex
+	minuteStart        time.Time
+	minuteUsed         int
+	dayStart           time.Time
+	dayUsed            int
+	rejectedUser       uint64
+	rejectedBackground uint64
+}
+
+// NewBudget creates a budget with the given limits and background reserve percentage
+func NewBudget(perMinute, perDay, reservePercent int) *Budget {
+	return &Budget{
+		perMinute: perMinute,
+		perDay:    perDay,
+		reserve:   reservePercent,
+		now:       time.Now,
+	}
+}
+
+// Acquire records one upstream call, or returns ErrBudgetExhausted if the
+// call's priority may not spend any more of the current windows
+func (b *Budget) Acquire(priority Priority) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roll(b.now())
+
+	minuteLimit, dayLimit := b.perMinute, b.perDay
+	if priority == PriorityBackground {
+		minuteLimit -= b.perMinute * b.reserve / 100
+		dayLimit -= b.perDay * b.reserve / 100
+	}
+
+	if b.minuteUsed >= minuteLimit || b.dayUsed >= dayLimit {
+		if priority == PriorityBackground {
+			b.rejectedBackground++
+		} else {
+			b.rejectedUser++
+		}
+		return ErrBudgetExhausted
+	}
+
+	b.minuteUsed++
+	b.dayUsed++
+	return nil
+}
+
+// RetryAfter returns how long until the exhausted window resets
+func (b *Budget) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.roll(now)
+	if b.dayUsed >= b.perDay {
+		return b.dayStart.AddDate(0, 0, 1).Sub(now)
+	}
+	return b.minuteStart.Add(time.Minute).Sub(now)
+}
+
+// Status returns current usage
+func (b *Budget) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roll(b.now())
+	return Status{
+		MinuteLimit:        b.perMinute,
+		MinuteUsed:         b.minuteUsed,
+		MinuteRemaining:    max(b.perMinute-b.minuteUsed, 0),
+		MinuteResetsAt:     b.minuteStart.Add(time.Minute),
+		DayLimit:           b.perDay,
+		DayUsed:            b.dayUsed,
+		DayRemaining:       max(b.perDay-b.dayUsed, 0),
+		DayResetsAt:        b.dayStart.AddDate(0, 0, 1),
+		BackgroundReserve:  b.reserve,
+		RejectedUser:       b.rejectedUser,
+		RejectedBackground: b.rejectedBackground,
+	}
+}
+
+// roll starts new windows once the current ones have passed; callers must hold b.mu
+func (b *Budget) roll(now time.Time) {
+	now = now.UTC()
+	if minute := now.Truncate(time.Minute); !minute.Equal(b.minuteStart) {
+		b.minuteStart = minute
+		b.minuteUsed = 0
+	}
+	if day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC); !day.Equal(b.dayStart) {
+		b.dayStart = day
+		b.dayUsed = 0
+	}
+}
@@ -0,0 +1,116 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestBudget returns a budget whose clock only moves when advance is called
+func newTestBudget(perMinute, perDay, reserve int, start time.Time) (*Budget, func(time.Duration)) {
+	now := start
+	b := NewBudget(perMinute, perDay, reserve)
+	b.now = func() time.Time { return now }
+	return b, func(d time.Duration) { now = now.Add(d) }
+}
+
+// acquireN calls Acquire n times and returns how many calls succeeded
+func acquireN(b *Budget, priority Priority, n int) int {
+	granted := 0
+	for i := 0; i < n; i++ {
+		if err := b.Acquire(priority); err == nil {
+			granted++
+		} else if !errors.Is(err, ErrBudgetExhausted) {
+			panic(err)
+		}
+	}
+	return granted
+}
+
+func TestBudgetMinuteWindow(t *testing.T) {
+	b, advance := newTestBudget(3, 100, 0, time.Date(2025, 1, 15, 10, 0, 30, 0, time.UTC))
+
+	if got := acquireN(b, PriorityUser, 5); got != 3 {
+		t.Fatalf("granted %d calls, want 3", got)
+	}
+	if got := b.RetryAfter(); got != 30*time.Second {
+		t.Errorf("RetryAfter = %v, want 30s", got)
+	}
+
+	// Windows are aligned to the minute, not to the first call
+	advance(29 * time.Second)
+	if err := b.Acquire(PriorityUser); err == nil {
+		t.Fatal("call allowed before the minute rolled over")
+	}
+	advance(time.Second)
+	if got := acquireN(b, PriorityUser, 5); got != 3 {
+		t.Errorf("granted %d calls in the next minute, want 3", got)
+	}
+
+	status := b.Status()
+	if status.MinuteUsed != 3 || status.MinuteRemaining != 0 || status.DayUsed != 6 || status.RejectedUser != 5 {
+		t.Errorf("status = %+v", status)
+	}
+	if want := time.Date(2025, 1, 15, 10, 2, 0, 0, time.UTC); !status.MinuteResetsAt.Equal(want) {
+		t.Errorf("minute resets at %v, want %v", status.MinuteResetsAt, want)
+	}
+}
+
+func TestBudgetDayWindow(t *testing.T) {
+	b, advance := newTestBudget(10, 4, 0, time.Date(2025, 1, 15, 23, 58, 0, 0, time.UTC))
+
+	if got := acquireN(b, PriorityUser, 3); got != 3 {
+		t.Fatalf("granted %d calls, want 3", got)
+	}
+	advance(time.Minute)
+	if got := acquireN(b, PriorityUser, 3); got != 1 {
+		t.Fatalf("granted %d calls after the minute rolled over, want the last 1 of the day", got)
+	}
+	if got := b.RetryAfter(); got != time.Minute {
+		t.Errorf("RetryAfter = %v, want the minute until midnight", got)
+	}
+
+	advance(time.Minute)
+	if got := acquireN(b, PriorityUser, 5); got != 4 {
+		t.Errorf("granted %d calls on the next day, want 4", got)
+	}
+	if status := b.Status(); !status.DayResetsAt.Equal(time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("day resets at %v", status.DayResetsAt)
+	}
+}
+
+func TestBudgetBackgroundReserve(t *testing.T) {
+	b, advance := newTestBudget(10, 1000, 20, time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC))
+
+	// Background calls stop at 80% of the minute, user calls may use the rest
+	if got := acquireN(b, PriorityBackground, 10); got != 8 {
+		t.Fatalf("granted %d background calls, want 8", got)
+	}
+	if got := acquireN(b, PriorityUser, 10); got != 2 {
+		t.Fatalf("granted %d user calls, want the 2 reserved", got)
+	}
+	status := b.Status()
+	if status.RejectedBackground != 2 || status.RejectedUser != 8 {
+		t.Errorf("rejected %d background and %d user calls, want 2 and 8", status.RejectedBackground, status.RejectedUser)
+	}
+
+	// User calls can eat into the background share too
+	advance(time.Minute)
+	if got := acquireN(b, PriorityUser, 9); got != 9 {
+		t.Fatalf("granted %d user calls, want 9", got)
+	}
+	if err := b.Acquire(PriorityBackground); err == nil {
+		t.Error("background call allowed within the reserve")
+	}
+}
+
+func TestPriorityFromContext(t *testing.T) {
+	if got := PriorityFromContext(context.Background()); got != PriorityUser {
+		t.Errorf("default priority = %v, want PriorityUser", got)
+	}
+	ctx := WithPriority(context.Background(), PriorityBackground)
+	if got := PriorityFromContext(ctx); got != PriorityBackground {
+		t.Errorf("priority = %v, want PriorityBackground", got)
+	}
+}
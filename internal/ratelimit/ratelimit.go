@@ -0,0 +1,137 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are evicted
+const sweepInterval = time.Minute
+
+// Tier holds token bucket settings
+type Tier struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter enforces a token bucket per client key. Memory use is bounded by
+// evicting the least recently used bucket once maxEntries is reached and
+// by dropping buckets idle for longer than idleTimeout.
+type Limiter struct {
+	maxEntries  int
+	idleTimeout time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element
+	lru       *list.List
+	lastSweep time.Time
+}
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter holding at most maxEntries buckets
+func NewLimiter(maxEntries int, idleTimeout time.Duration) *Limiter {
+	return &Limiter{
+		maxEntries:  maxEntries,
+		idleTimeout: idleTimeout,
+		now:         time.Now,
+		buckets:     make(map[string]*list.Element),
+		lru:         list.New(),
+		lastSweep:   time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket for key, refilled according to tier
+func (l *Limiter) Allow(key string, tier Tier) Result {
+	return l.take(key, tier, true)
+}
+
+// Peek reports whether the bucket for key holds a token, like Allow, without taking it
+func (l *Limiter) Peek(key string, tier Tier) Result {
+	return l.take(key, tier, false)
+}
+
+// take refills the bucket for key and takes a token from it when consume is set
+func (l *Limiter) take(key string, tier Tier, consume bool) Result {
+	now := l.now()
+	rate := float64(tier.RequestsPerMinute) / 60 // tokens per second
+	burst := float64(tier.Burst)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	var b *bucket
+	if elem, ok := l.buckets[key]; ok {
+		b = elem.Value.(*bucket)
+		l.lru.MoveToFront(elem)
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+	} else {
+		if l.lru.Len() >= l.maxEntries {
+			l.evict(l.lru.Back())
+		}
+		b = &bucket{key: key, tokens: burst, last: now}
+		l.buckets[key] = l.lru.PushFront(b)
+	}
+
+	result := Result{Limit: tier.Burst}
+	if b.tokens >= 1 {
+		if consume {
+			b.tokens--
+		}
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = secondsToDuration((burst - b.tokens) / rate)
+	return result
+}
+
+// sweep evicts buckets idle longer than the idle timeout; callers must hold l.mu
+func (l *Limiter) sweep(now time.Time) {
+	for elem := l.lru.Back(); elem != nil; {
+		b := elem.Value.(*bucket)
+		if now.Sub(b.last) < l.idleTimeout {
+			// The list is ordered by last use, so everything in front is newer
+			break
+		}
+		prev := elem.Prev()
+		l.evict(elem)
+		elem = prev
+	}
+	l.lastSweep = now
+}
+
+// evict removes a bucket; callers must hold l.mu
+func (l *Limiter) evict(elem *list.Element) {
+	if elem == nil {
+		return
+	}
+	l.lru.Remove(elem)
+	delete(l.buckets, elem.Value.(*bucket).key)
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter whose clock only moves when advance is called
+func newTestLimiter(maxEntries int, idleTimeout time.Duration) (*Limiter, func(time.Duration)) {
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	l := NewLimiter(maxEntries, idleTimeout)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLimiterTokenBucket(t *testing.T) {
+	l, advance := newTestLimiter(10, time.Hour)
+	tier := Tier{RequestsPerMinute: 60, Burst: 3}
+
+	// The bucket starts full
+	for i := 2; i >= 0; i-- {
+		result := l.Allow("client", tier)
+		if !result.Allowed || result.Remaining != i || result.Limit != 3 {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", 3-i, result, i)
+		}
+	}
+
+	result := l.Allow("client", tier)
+	if result.Allowed {
+		t.Fatal("request beyond the burst was allowed")
+	}
+	if result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("retry after %v, reset %v; want 1s and 3s", result.RetryAfter, result.Reset)
+	}
+
+	// Tokens refill at one per second and never exceed the burst
+	advance(1500 * time.Millisecond)
+	if result := l.Allow("client", tier); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after 1.5s = %+v, want allowed with 0 remaining", result)
+	}
+	advance(time.Hour)
+	if result := l.Allow("client", tier); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("after an hour = %+v, want allowed with 2 remaining", result)
+	}
+
+	// Other keys have their own buckets
+	if result := l.Allow("other", tier); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("other key = %+v, want a full bucket", result)
+	}
+}
+
+func TestLimiterPeek(t *testing.T) {
+	l, _ := newTestLimiter(10, time.Hour)
+	tier := Tier{RequestsPerMinute: 60, Burst: 1}
+
+	for i := 0; i < 3; i++ {
+		if result := l.Peek("client", tier); !result.Allowed || result.Remaining != 1 {
+			t.Fatalf("peek %d = %+v, want allowed without taking the token", i+1, result)
+		}
+	}
+	l.Allow("client", tier)
+	if result := l.Peek("client", tier); result.Allowed || result.RetryAfter != time.Second {
+		t.Errorf("peek at an empty bucket = %+v, want refused with a 1s retry", result)
+	}
+}
+
+func TestLimiterEvictsLeastRecentlyUsed(t *testing.T) {
+	l, _ := newTestLimiter(2, time.Hour)
+	tier := Tier{RequestsPerMinute: 1, Burst: 1}
+
+	l.Allow("a", tier)
+	l.Allow("b", tier)
+	l.Allow("a", tier) // a is now the most recently used
+	l.Allow("c", tier) // evicts b
+
+	if _, ok := l.buckets["b"]; ok {
+		t.Error("least recently used bucket b was kept")
+	}
+	if l.lru.Len() != 2 {
+		t.Errorf("tracking %d buckets, want 2", l.lru.Len())
+	}
+	// An evicted client starts over with a full bucket
+	if result := l.Allow("b", tier); !result.Allowed {
+		t.Error("evicted client was limited")
+	}
+	if result := l.Allow("c", tier); result.Allowed {
+		t.Error("tracked client got a fresh bucket")
+	}
+}
+
+func TestLimiterSweepsIdleBuckets(t *testing.T) {
+	l, advance := newTestLimiter(10, 5*time.Minute)
+	tier := Tier{RequestsPerMinute: 60, Burst: 5}
+
+	l.Allow("idle", tier)
+	advance(4 * time.Minute)
+	l.Allow("active", tier)
+	advance(2 * time.Minute)
+	l.Allow("active", tier)
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("active bucket was swept")
+	}
+}
@@ -1,50 +1,309 @@
 package routes
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/cache"
+	"github.com/ANAS727189/weather-project/internal/collector"
 	"github.com/ANAS727189/weather-project/internal/config"
 	"github.com/ANAS727189/weather-project/internal/handlers"
+	"github.com/ANAS727189/weather-project/internal/logging"
+	"github.com/ANAS727189/weather-project/internal/metrics"
 	"github.com/ANAS727189/weather-project/internal/middleware"
+	"github.com/ANAS727189/weather-project/internal/quota"
+	"github.com/ANAS727189/weather-project/internal/ratelimit"
 	"github.com/ANAS727189/weather-project/internal/services"
+	"github.com/ANAS727189/weather-project/internal/storage"
+	"github.com/ANAS727189/weather-project/internal/utils"
 	"github.com/gorilla/mux"
 )
 
+// unauthenticatedPaths are served without API keys or rate limits so load balancers can reach them
+var unauthenticatedPaths = []string{"/api/v1/health", "/livez", "/readyz"}
+
 // Router holds all the route configurations
 type Router struct {
 	config         *config.Config
 	weatherHandler *handlers.WeatherHandler
 	healthHandler  *handlers.HealthHandler
+	historyHandler *handlers.HistoryHandler
+	adminHandler   *handlers.AdminHandler
+	streamHandler  *handlers.StreamHandler
+	socketHandler  *handlers.SocketHandler
+	refresher      *services.Refresher
+	cache          cache.Backend
+	store          storage.Store
+	retention      *storage.RetentionWorker
+	collector      *collector.Collector
+	authenticator  *auth.Authenticator
+	cors           *middleware.CORS
+	rateLimiter    *middleware.RateLimiter
+	weatherService *services.WeatherService
 }
 
 // NewRouter creates a new router instance
-func NewRouter(cfg *config.Config) *Router {
+func NewRouter(cfg *config.Config) (*Router, error) {
+	// Initialize authentication
+	var authenticator *auth.Authenticator
+	if cfg.Auth.Enabled {
+		var err error
+		authenticator, err = auth.NewAuthenticator(cfg.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize authentication: %v", err)
+		}
+	}
+
+	// Initialize rate limiting
+	var rateLimiter *middleware.RateLimiter
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.NewLimiter(cfg.RateLimit.MaxEntries, time.Duration(cfg.RateLimit.IdleTimeout)*time.Second)
+		var err error
+		rateLimiter, err = middleware.NewRateLimiter(limiter, cfg.RateLimit, unauthenticatedPaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure rate limiting: %v", err)
+		}
+	}
+
+	// Initialize storage
+	var store storage.Store
+	var retention *storage.RetentionWorker
+	if cfg.Storage.Enabled {
+		var err error
+		store, err = storage.Open(cfg.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open history store: %v", err)
+		}
+		if cfg.Storage.RetentionDays > 0 {
+			retention = storage.NewRetentionWorker(store, time.Duration(cfg.Storage.RetentionDays)*24*time.Hour, time.Hour)
+		}
+		slog.Info("recording observation history", "driver", cfg.Storage.Driver, "path", cfg.Storage.Path)
+	}
+
+	// Initialize upstream budget
+	var budget *quota.Budget
+	if cfg.Quota.Enabled {
+		budget = quota.NewBudget(cfg.Quota.PerMinute, cfg.Quota.PerDay, cfg.Quota.BackgroundReserve)
+	}
+
+	// Initialize the upstream circuit breaker
+	var upstreamBreaker *breaker.Breaker
+	if cfg.Breaker.Enabled {
+		upstreamBreaker = breaker.New(cfg.Breaker.FailureThreshold, time.Duration(cfg.Breaker.Cooldown)*time.Second)
+	}
+
+	// Initialize the response cache
+	var cacheBackend cache.Backend
+	if cfg.Cache.Enabled {
+		var err error
+		cacheBackend, err = cache.Open(cfg.Cache)
+		if err != nil {
+			if store != nil {
+				store.Close()
+			}
+			return nil, fmt.Errorf("failed to open response cache: %v", err)
+		}
+		slog.Info("caching upstream responses", "backend", cfg.Cache.Backend)
+	}
+
 	// Initialize services
-	weatherService := services.NewWeatherService(cfg)
-	healthService := services.NewHealthService("1.0.0")
+	weatherService := services.NewWeatherService(cfg, cacheBackend, store, budget, upstreamBreaker)
+	healthService := services.NewHealthService(time.Duration(cfg.Health.CheckTimeout) * time.Second)
+	historyService := services.NewHistoryService(store)
+
+	// Readiness checks
+	if cfg.Health.UpstreamProbe {
+		healthService.RegisterCheck("upstream", func(ctx context.Context) error {
+			return weatherService.ProbeUpstream(ctx, cfg.Health.ProbeCity)
+		}, time.Duration(cfg.Health.ProbeInterval)*time.Second)
+	}
+	if upstreamBreaker != nil {
+		healthService.RegisterCheck("circuit_breaker", func(ctx context.Context) error {
+			return upstreamBreaker.Ready()
+		}, 0)
+	}
+	if cfg.Cache.Enabled {
+		healthService.RegisterCheck("cache", weatherService.PingCache, 0)
+	}
+	if store != nil {
+		healthService.RegisterCheck("storage", store.Ping, 0)
+	}
+
+	var coll *collector.Collector
+	if cfg.Collector.Enabled {
+		var err error
+		coll, err = collector.New(cfg.Collector, weatherService)
+		if err != nil {
+			if store != nil {
+				store.Close()
+			}
+			if cacheBackend != nil {
+				cacheBackend.Close()
+			}
+			return nil, err
+		}
+		healthService.RegisterComponent("collector", func() interface{} {
+			return coll.Status()
+		})
+	}
+	refresher := services.NewRefresher(
+		weatherService,
+		time.Duration(cfg.Stream.RefreshInterval)*time.Second,
+		time.Duration(cfg.Stream.ForecastRefreshInterval)*time.Second,
+	)
 
 	// Initialize handlers
 	weatherHandler := handlers.NewWeatherHandler(weatherService)
 	healthHandler := handlers.NewHealthHandler(healthService)
+	historyHandler := handlers.NewHistoryHandler(historyService, cfg.History)
+	adminHandler := handlers.NewAdminHandler(weatherService, cfg)
+	streamHandler := handlers.NewStreamHandler(
+		refresher,
+		cfg.Stream.MaxConnections,
+		cfg.Stream.MaxCities,
+		time.Duration(cfg.Stream.HeartbeatInterval)*time.Second,
+	)
+	// WebSocket upgrades follow the CORS origins, including after a reload
+	cors := middleware.NewCORS(cfg.Server.CORS)
+	socketHandler := handlers.NewSocketHandler(
+		refresher,
+		cors.AllowsOrigin,
+		cfg.Stream.MaxConnections,
+		cfg.Stream.MaxCities,
+		time.Duration(cfg.Stream.HeartbeatInterval)*time.Second,
+	)
 
 	return &Router{
 		config:         cfg,
 		weatherHandler: weatherHandler,
 		healthHandler:  healthHandler,
+		historyHandler: historyHandler,
+		adminHandler:   adminHandler,
+		streamHandler:  streamHandler,
+		socketHandler:  socketHandler,
+		refresher:      refresher,
+		cache:          cacheBackend,
+		store:          store,
+		retention:      retention,
+		collector:      coll,
+		authenticator:  authenticator,
+		cors:           cors,
+		rateLimiter:    rateLimiter,
+		weatherService: weatherService,
+	}, nil
+}
+
+// Start launches background workers such as retention pruning and the scheduled collector
+func (router *Router) Start() {
+	if router.retention != nil {
+		router.retention.Start()
+	}
+	if router.collector != nil {
+		router.collector.Start()
 	}
 }
 
+// Shutdown closes WebSocket connections, ends open streams and stops
+// background workers. Storage and the cache backend stay open so requests
+// still draining can use them; call Close once the listeners have stopped.
+func (router *Router) Shutdown(ctx context.Context) error {
+	err := router.socketHandler.Shutdown(ctx)
+	router.refresher.Close()
+
+	if router.collector != nil {
+		router.collector.Stop()
+	}
+	if router.retention != nil {
+		router.retention.Stop()
+	}
+	return err
+}
+
+// Close closes storage and the cache backend
+func (router *Router) Close() error {
+	var err error
+	if router.store != nil {
+		err = router.store.Close()
+	}
+	if router.cache != nil {
+		if closeErr := router.cache.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Reload applies the runtime-reloadable settings of cfg to running components.
+// Nothing is changed if any setting is rejected.
+func (router *Router) Reload(cfg *config.Config) error {
+	if _, err := logging.ParseLevel(cfg.Log.Level); err != nil {
+		return err
+	}
+	if router.rateLimiter != nil {
+		if err := router.rateLimiter.Update(cfg.RateLimit); err != nil {
+			return fmt.Errorf("invalid rate limit settings: %v", err)
+		}
+	}
+
+	logging.SetLevel(cfg.Log.Level)
+	router.cors.Update(cfg.Server.CORS)
+	router.weatherService.SetConfig(cfg)
+	router.adminHandler.SetConfig(cfg)
+	router.config = cfg
+	return nil
+}
+
 // SetupRoutes configures all routes and middleware
-func (router *Router) SetupRoutes() *mux.Router {
+func (router *Router) SetupRoutes() http.Handler {
 	r := mux.NewRouter()
 
 	// Apply global middleware
+	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.TracingMiddleware)
 	r.Use(middleware.LoggingMiddleware)
+	if router.config.Metrics.Enabled {
+		r.Use(middleware.RecordRoute)
+	}
 	r.Use(middleware.RecoveryMiddleware)
-	r.Use(middleware.CORSMiddleware(
-		router.config.Server.CORS.AllowedOrigins,
-		router.config.Server.CORS.AllowedMethods,
-		router.config.Server.CORS.AllowedHeaders,
-		router.config.Server.CORS.AllowCredentials,
-	))
+	if compression := router.config.Server.Compression; compression.Enabled {
+		r.Use(middleware.CompressionMiddleware(compression.MinSize))
+	}
+	r.Use(router.cors.Middleware)
+	if router.authenticator != nil {
+		// Failed attempts are limited per IP before credentials are checked,
+		// since consumer buckets below only apply once authentication succeeds
+		if router.rateLimiter != nil {
+			r.Use(router.rateLimiter.LimitAuthFailures)
+		}
+		r.Use(middleware.AuthMiddleware(router.authenticator, unauthenticatedPaths))
+		if len(router.config.Auth.RouteScopes) > 0 {
+			r.Use(middleware.RequireRouteScopes(router.config.Auth.RouteScopes))
+		}
+	}
+	if router.rateLimiter != nil {
+		r.Use(router.rateLimiter.Middleware)
+	}
+
+	// Liveness and readiness probes
+	r.HandleFunc("/livez", router.healthHandler.GetLiveness).Methods("GET").Name("livez")
+	r.HandleFunc("/readyz", router.healthHandler.GetReadiness).Methods("GET").Name("readyz")
+
+	// Prometheus metrics move to the admin listener when it is enabled. They
+	// reveal routes and traffic, so the public API serves them only to callers
+	// with the admin scope.
+	if router.config.Metrics.Enabled && !router.config.Admin.Enabled {
+		if router.authenticator != nil {
+			r.Handle("/metrics", middleware.RequireScope("admin")(metrics.Handler())).Methods("GET").Name("metrics")
+		} else {
+			slog.Warn("not serving /metrics on the public API without authentication; enable auth or the admin listener")
+		}
+	}
 
 	// API v1 routes
 	api := r.PathPrefix("/api/v1").Subrouter()
@@ -53,20 +312,87 @@ func (router *Router) SetupRoutes() *mux.Router {
 	// Legacy routes for backward compatibility
 	router.setupLegacyRoutes(r)
 
+	// Metrics wrap the router, since mux only runs middleware for matched
+	// routes and 404 and 405 answers must be counted too
+	if router.config.Metrics.Enabled {
+		return middleware.MetricsMiddleware(r)
+	}
 	return r
 }
 
 // setupAPIRoutes configures the API v1 routes
 func (router *Router) setupAPIRoutes(api *mux.Router) {
 	// Health check
-	api.HandleFunc("/health", router.healthHandler.GetHealth).Methods("GET")
+	api.HandleFunc("/health", router.healthHandler.GetHealth).Methods("GET").Name("health")
 
 	// Weather routes
-	api.HandleFunc("/weather/{city}", router.weatherHandler.GetCurrentWeather).Methods("GET")
-	api.HandleFunc("/forecast/{city}", router.weatherHandler.GetForecast).Methods("GET")
+	mappable := middleware.ContentNegotiation(utils.FormatJSON, utils.FormatGeoJSON)
+	tabular := middleware.ContentNegotiation(utils.FormatJSON, utils.FormatCSV, utils.FormatXML, utils.FormatNDJSON)
+	tabularMappable := middleware.ContentNegotiation(utils.FormatJSON, utils.FormatCSV, utils.FormatXML, utils.FormatNDJSON, utils.FormatGeoJSON)
+	api.Handle("/weather", tabularMappable(http.HandlerFunc(router.weatherHandler.GetBatchWeather))).Methods("GET").Name("weather.batch")
+	api.Handle("/weather/{city}", mappable(http.HandlerFunc(router.weatherHandler.GetCurrentWeather))).Methods("GET").Name("weather")
+	api.Handle("/forecast/{city}", tabular(http.HandlerFunc(router.weatherHandler.GetForecast))).Methods("GET").Name("forecast")
+	api.Handle("/alerts/{city}", tabularMappable(http.HandlerFunc(router.weatherHandler.GetAlerts))).Methods("GET").Name("alerts")
+	api.Handle("/history/{city}", tabular(http.HandlerFunc(router.historyHandler.GetHistory))).Methods("GET").Name("history")
+
+	// Live update streams
+	api.HandleFunc("/stream/weather", router.streamHandler.StreamWeather).Methods("GET").Name("stream")
+	api.HandleFunc("/ws", router.socketHandler.Subscribe).Methods("GET").Name("ws")
+
+	// Admin routes can flush the cache, spend upstream quota and reveal the
+	// deployment layout, so the public API serves them only to callers with the
+	// admin scope. Without authentication they are left to the admin listener.
+	if router.config.Admin.Enabled {
+		return
+	}
+	if router.authenticator == nil {
+		slog.Warn("not serving /api/v1/admin routes on the public API without authentication; enable auth or the admin listener")
+		return
+	}
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.RequireScope("admin"))
+	router.setupAdminRoutes(admin)
+}
+
+// setupAdminRoutes configures operational routes shared by the public API and the admin listener
+func (router *Router) setupAdminRoutes(admin *mux.Router) {
+	admin.HandleFunc("/quota", router.adminHandler.GetQuota).Methods("GET").Name("admin.quota")
+	admin.HandleFunc("/config", router.adminHandler.GetConfig).Methods("GET").Name("admin.config")
+	admin.HandleFunc("/breaker", router.adminHandler.GetBreaker).Methods("GET").Name("admin.breaker")
+	admin.HandleFunc("/breaker/{action}", router.adminHandler.ControlBreaker).Methods("POST").Name("admin.breaker.control")
+	admin.HandleFunc("/cache", router.adminHandler.GetCache).Methods("GET").Name("admin.cache")
+	admin.HandleFunc("/cache", router.adminHandler.PurgeCache).Methods("DELETE").Name("admin.cache.purge")
+	admin.HandleFunc("/cache/entries", router.adminHandler.GetCacheEntries).Methods("GET").Name("admin.cache.entries")
+	admin.HandleFunc("/cache/prewarm", router.adminHandler.PrewarmCache).Methods("POST").Name("admin.cache.prewarm")
+}
+
+// SetupAdminRoutes configures the admin listener: metrics, pprof and the
+// admin endpoints, all behind the admin token
+func (router *Router) SetupAdminRoutes() *mux.Router {
+	r := mux.NewRouter()
+
+	r.Use(middleware.RequestIDMiddleware)
+	r.Use(middleware.LoggingMiddleware)
+	r.Use(middleware.RecoveryMiddleware)
+	r.Use(middleware.AdminTokenMiddleware(router.config.Admin.Token))
+
+	if router.config.Metrics.Enabled {
+		r.Handle("/metrics", metrics.Handler()).Methods("GET").Name("metrics")
+	}
+	if router.config.Admin.Pprof {
+		r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline).Name("pprof.cmdline")
+		r.HandleFunc("/debug/pprof/profile", pprof.Profile).Name("pprof.profile")
+		r.HandleFunc("/debug/pprof/symbol", pprof.Symbol).Name("pprof.symbol")
+		r.HandleFunc("/debug/pprof/trace", pprof.Trace).Name("pprof.trace")
+		// Index also serves the named profiles such as heap and goroutine
+		r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index).Name("pprof")
+	}
+
+	router.setupAdminRoutes(r.PathPrefix("/admin").Subrouter())
+	return r
 }
 
 // setupLegacyRoutes configures legacy routes for backward compatibility
 func (router *Router) setupLegacyRoutes(r *mux.Router) {
-	r.HandleFunc("/weather/{city}", router.weatherHandler.GetCurrentWeatherLegacy).Methods("GET")
+	r.HandleFunc("/weather/{city}", router.weatherHandler.GetCurrentWeatherLegacy).Methods("GET").Name("legacy.weather")
 }
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ANAS727189/weather-project/internal/auth"
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// newTestRouter returns a router for the default configuration with an
+// admin-scoped API key, adjusted by configure, that makes no upstream calls
+func newTestRouter(t *testing.T, configure func(*config.Config)) *Router {
+	t.Helper()
+
+	t.Setenv("OPENWEATHER_API_KEY", "test-key")
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	cfg.API.BaseURL = "http://127.0.0.1:0"
+	cfg.Storage.Enabled = false
+	cfg.Collector.Enabled = false
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []config.APIKeyConfig{{ID: "ops", Hash: auth.HashKey("ops-key"), Scopes: []string{"admin"}}}
+	cfg.Admin.Token = "admin-token"
+	configure(cfg)
+
+	router, err := NewRouter(cfg)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	t.Cleanup(func() { router.Close() })
+	return router
+}
+
+func TestAdminRoutesPlacement(t *testing.T) {
+	adminPaths := []struct {
+		method string
+		public string
+		admin  string
+	}{
+		{http.MethodGet, "/api/v1/admin/quota", "/admin/quota"},
+		{http.MethodGet, "/api/v1/admin/config", "/admin/config"},
+		{http.MethodGet, "/api/v1/admin/cache", "/admin/cache"},
+		{http.MethodGet, "/api/v1/admin/breaker", "/admin/breaker"},
+		{http.MethodPost, "/api/v1/admin/breaker/reset", "/admin/breaker/reset"},
+		{http.MethodGet, "/metrics", "/metrics"},
+	}
+
+	serve := func(handler http.Handler, method, path, header, value string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set(header, value)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("admin listener enabled", func(t *testing.T) {
+		router := newTestRouter(t, func(cfg *config.Config) { cfg.Admin.Enabled = true })
+		public, admin := router.SetupRoutes(), router.SetupAdminRoutes()
+
+		for _, p := range adminPaths {
+			if got := serve(public, p.method, p.public, "X-API-Key", "ops-key"); got != http.StatusNotFound {
+				t.Errorf("public %s %s = %d, want 404", p.method, p.public, got)
+			}
+			if got := serve(admin, p.method, p.admin, "Authorization", "Bearer admin-token"); got != http.StatusOK {
+				t.Errorf("admin listener %s %s = %d, want 200", p.method, p.admin, got)
+			}
+			if got := serve(admin, p.method, p.admin, "Authorization", "Bearer wrong"); got != http.StatusUnauthorized {
+				t.Errorf("admin listener %s %s with a wrong token = %d, want 401", p.method, p.admin, got)
+			}
+		}
+	})
+
+	t.Run("admin listener disabled", func(t *testing.T) {
+		router := newTestRouter(t, func(cfg *config.Config) { cfg.Admin.Enabled = false })
+		public := router.SetupRoutes()
+
+		for _, p := range adminPaths {
+			if got := serve(public, p.method, p.public, "X-API-Key", "ops-key"); got != http.StatusOK {
+				t.Errorf("public %s %s = %d, want 200", p.method, p.public, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
+)
+
+// ErrInvalidPattern is returned for cache key patterns that are not well-formed globs
+var ErrInvalidPattern = errors.New("invalid cache key pattern")
+
+// CachingEnabled reports whether upstream responses are cached
+func (ws *WeatherService) CachingEnabled() bool {
+	return ws.cache != nil
+}
+
+// CacheStats summarises the response cache. The memory estimate is the size of
+// each entry's key and encoded value, not the exact heap usage.
+func (ws *WeatherService) CacheStats(ctx context.Context) (models.CacheStats, error) {
+	stats := models.CacheStats{ByKind: make(map[string]int)}
+	if ws.cache == nil {
+		return stats, nil
+	}
+
+	all, err := ws.cache.Entries(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list cache entries: %v", err)
+	}
+	now := time.Now()
+	for _, e := range all {
+		stats.Entries++
+		if now.After(e.ExpiresAt) {
+			stats.Stale++
+		} else {
+			stats.Fresh++
+		}
+		kind, _, _ := strings.Cut(e.Key, "|")
+		stats.ByKind[kind]++
+		stats.EstimatedBytes += len(e.Key) + len(e.Value)
+	}
+	return stats, nil
+}
+
+// CacheEntries lists cached responses whose key matches the glob pattern, or
+// all of them when pattern is empty. Keys have the form "kind|city:name" or
+// "kind|coord:lat,lon", e.g. "forecast|city:london".
+func (ws *WeatherService) CacheEntries(ctx context.Context, pattern string) ([]models.CacheEntry, error) {
+	if err := validPattern(pattern); err != nil {
+		return nil, err
+	}
+	entries := []models.CacheEntry{}
+	if ws.cache == nil {
+		return entries, nil
+	}
+
+	all, err := ws.cache.Entries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cache entries: %v", err)
+	}
+	now := time.Now()
+	for _, e := range all {
+		if pattern != "" && !matchKey(pattern, e.Key) {
+			continue
+		}
+		kind, location, _ := strings.Cut(e.Key, "|")
+		entries = append(entries, models.CacheEntry{
+			Key:        e.Key,
+			Kind:       kind,
+			Location:   location,
+			StoredAt:   e.StoredAt,
+			ExpiresAt:  e.ExpiresAt,
+			AgeSeconds: int(now.Sub(e.StoredAt).Seconds()),
+			TTLSeconds: int(e.ExpiresAt.Sub(now).Seconds()),
+			Stale:      now.After(e.ExpiresAt),
+			SizeBytes:  len(e.Key) + len(e.Value),
+		})
+	}
+	return entries, nil
+}
+
+// PurgeCache removes every cached response and returns how many were removed
+func (ws *WeatherService) PurgeCache(ctx context.Context) (int, error) {
+	if ws.cache == nil {
+		return 0, nil
+	}
+	return ws.cache.Clear(ctx)
+}
+
+// PurgeCacheLocation removes the cached responses of every kind for a location
+func (ws *WeatherService) PurgeCacheLocation(ctx context.Context, loc models.Location) (int, error) {
+	if ws.cache == nil {
+		return 0, nil
+	}
+	suffix := "|" + loc.Key()
+	return ws.cache.DeleteFunc(ctx, func(key string) bool {
+		return strings.HasSuffix(key, suffix)
+	})
+}
+
+// PurgeCacheMatching removes cached responses whose key matches the glob
+// pattern. Malformed patterns are reported as ErrInvalidPattern.
+func (ws *WeatherService) PurgeCacheMatching(ctx context.Context, pattern string) (int, error) {
+	if err := validPattern(pattern); err != nil {
+		return 0, err
+	}
+	if ws.cache == nil {
+		return 0, nil
+	}
+	return ws.cache.DeleteFunc(ctx, func(key string) bool {
+		return matchKey(pattern, key)
+	})
+}
+
+// PrewarmCache fetches current weather and forecasts for cities into the
+// cache. Calls are charged to the background budget so pre-warming cannot
+// starve interactive requests.
+func (ws *WeatherService) PrewarmCache(ctx context.Context, cities []string) []models.CachePrewarmResult {
+	ctx = quota.WithPriority(ctx, quota.PriorityBackground)
+
+	results := make([]models.CachePrewarmResult, 0, len(cities))
+	for _, city := range cities {
+		loc := models.CityLocation(city)
+		result := models.CachePrewarmResult{City: loc.City, Weather: "ok", Forecast: "ok"}
+		if _, err := ws.RefreshCurrentWeather(ctx, loc); err != nil {
+			result.Weather = err.Error()
+		}
+		if _, err := ws.RefreshForecast(ctx, loc); err != nil {
+			result.Forecast = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// validPattern checks that pattern is empty or a well-formed glob
+func validPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ErrInvalidPattern
+	}
+	return nil
+}
+
+// matchKey matches a glob against a cache key; "*" also spans the "|" and ":" separators
+func matchKey(pattern, key string) bool {
+	matched, _ := path.Match(pattern, key)
+	return matched
+}
@@ -1,34 +1,175 @@
 package services
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/version"
 )
 
+// Readiness and check statuses
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+	CheckUp        = "up"
+	CheckDown      = "down"
+)
+
+// Check reports whether a dependency is usable; a nil error means healthy
+type Check func(ctx context.Context) error
+
 // HealthService handles health check operations
 type HealthService struct {
-	startTime time.Time
-	version   string
+	startTime    time.Time
+	checkTimeout time.Duration
+
+	mu         sync.RWMutex
+	components map[string]func() interface{}
+	checks     map[string]*checker
+}
+
+// checker runs a readiness check, reusing its last result for cacheFor
+type checker struct {
+	check    Check
+	cacheFor time.Duration
+
+	mu   sync.Mutex
+	last models.CheckResult
 }
 
-// NewHealthService creates a new health service instance
-func NewHealthService(version string) *HealthService {
+// NewHealthService creates a new health service instance. Readiness checks
+// are abandoned after checkTimeout.
+func NewHealthService(checkTimeout time.Duration) *HealthService {
 	return &HealthService{
-		startTime: time.Now(),
-		version:   version,
+		startTime:    time.Now(),
+		checkTimeout: checkTimeout,
+		components:   make(map[string]func() interface{}),
+		checks:       make(map[string]*checker),
 	}
 }
 
+// RegisterComponent adds a named status report to health responses
+func (hs *HealthService) RegisterComponent(name string, status func() interface{}) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.components[name] = status
+}
+
+// RegisterCheck adds a named readiness check. When cacheFor is positive the
+// last result is reused for that long so expensive probes are not repeated
+// on every readiness request.
+func (hs *HealthService) RegisterCheck(name string, check Check, cacheFor time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	hs.checks[name] = &checker{check: check, cacheFor: cacheFor}
+}
+
 // GetHealthStatus returns the current health status
 func (hs *HealthService) GetHealthStatus() *models.HealthResponse {
 	uptime := time.Since(hs.startTime)
 
-	return &models.HealthResponse{
+	response := &models.HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now(),
-		Version:   hs.version,
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildTime: version.BuildTime,
 		Uptime:    uptime.String(),
 		Service:   "weather-api",
 	}
+
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	if len(hs.components) > 0 {
+		response.Components = make(map[string]interface{}, len(hs.components))
+		for name, status := range hs.components {
+			response.Components[name] = status()
+		}
+	}
+
+	return response
+}
+
+// Live reports that the process is running; it never inspects dependencies
+func (hs *HealthService) Live() *models.LivenessResponse {
+	return &models.LivenessResponse{
+		Status:  "alive",
+		Version: version.Version,
+		Uptime:  time.Since(hs.startTime).String(),
+	}
+}
+
+// Ready runs every readiness check concurrently and reports not ready if any is down
+func (hs *HealthService) Ready(ctx context.Context) *models.ReadinessResponse {
+	hs.mu.RLock()
+	checks := make(map[string]*checker, len(hs.checks))
+	for name, c := range hs.checks {
+		checks[name] = c
+	}
+	hs.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, hs.checkTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]models.CheckResult, len(checks))
+	for name, c := range checks {
+		wg.Add(1)
+		go func(name string, c *checker) {
+			defer wg.Done()
+			result := c.run(ctx)
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, c)
+	}
+	wg.Wait()
+
+	status := StatusReady
+	for _, result := range results {
+		if result.Status != CheckUp {
+			status = StatusNotReady
+		}
+	}
+
+	return &models.ReadinessResponse{
+		Status:    status,
+		Timestamp: time.Now(),
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildTime: version.BuildTime,
+		Checks:    results,
+	}
+}
+
+// run returns the cached result if it is recent enough, otherwise runs the check.
+// Concurrent callers wait for a single in-flight check rather than starting their own.
+func (c *checker) run(ctx context.Context) models.CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cacheFor > 0 && !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < c.cacheFor {
+		return c.last
+	}
+
+	start := time.Now()
+	err := c.check(ctx)
+	result := models.CheckResult{
+		Status:    CheckUp,
+		Latency:   time.Since(start).Round(time.Microsecond).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = CheckDown
+		result.Error = err.Error()
+	}
+
+	c.last = result
+	return result
 }
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadinessCachesProbeResults(t *testing.T) {
+	hs := NewHealthService(time.Second)
+
+	var probes, pings atomic.Int32
+	probeErr := errors.New("upstream unreachable")
+	hs.RegisterCheck("upstream", func(ctx context.Context) error {
+		probes.Add(1)
+		return probeErr
+	}, time.Minute)
+	hs.RegisterCheck("storage", func(ctx context.Context) error {
+		pings.Add(1)
+		return nil
+	}, 0)
+
+	first := hs.Ready(context.Background())
+	for i := 0; i < 2; i++ {
+		ready := hs.Ready(context.Background())
+		if got := ready.Checks["upstream"]; got != first.Checks["upstream"] {
+			t.Errorf("cached upstream result = %+v, want %+v", got, first.Checks["upstream"])
+		}
+	}
+	if got := probes.Load(); got != 1 {
+		t.Errorf("upstream probed %d times within its interval, want 1", got)
+	}
+	if got := pings.Load(); got != 3 {
+		t.Errorf("uncached storage check ran %d times, want 3", got)
+	}
+	if first.Status != StatusNotReady || first.Checks["upstream"].Error != probeErr.Error() {
+		t.Errorf("readiness = %+v, want the cached failure to keep it not ready", first)
+	}
+
+	// Once the interval has passed the probe runs again
+	probeErr = nil
+	hs.checks["upstream"].last.CheckedAt = time.Now().Add(-time.Minute)
+	if ready := hs.Ready(context.Background()); ready.Status != StatusReady || probes.Load() != 2 {
+		t.Errorf("after the interval: status %s with %d probes, want ready after a second probe", ready.Status, probes.Load())
+	}
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	hs := NewHealthService(20 * time.Millisecond)
+	hs.RegisterCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, 0)
+
+	ready := hs.Ready(context.Background())
+	if ready.Status != StatusNotReady || ready.Checks["slow"].Status != CheckDown {
+		t.Errorf("readiness = %+v, want the timed out check down", ready)
+	}
+}
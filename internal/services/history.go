@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/storage"
+)
+
+// History aggregation intervals
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalMonth = "month"
+)
+
+// HistoryService handles queries over recorded observations
+type HistoryService struct {
+	store storage.Store
+}
+
+// NewHistoryService creates a new history service; store may be nil when storage is disabled
+func NewHistoryService(store storage.Store) *HistoryService {
+	return &HistoryService{
+		store: store,
+	}
+}
+
+// Enabled reports whether observation history is available
+func (hs *HistoryService) Enabled() bool {
+	return hs.store != nil
+}
+
+// GetHistory returns observations for a location aggregated into interval buckets, oldest first.
+// Intervals without observations are omitted.
+func (hs *HistoryService) GetHistory(ctx context.Context, loc models.Location, from, to time.Time, interval string) ([]models.HistoryBucket, error) {
+	if hs.store == nil {
+		return nil, fmt.Errorf("history storage is not enabled")
+	}
+
+	observations, err := hs.store.Query(ctx, loc.Key(), from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %v", err)
+	}
+
+	var buckets []models.HistoryBucket
+	var acc *bucketAccumulator
+	for _, obs := range observations {
+		start := truncateToInterval(obs.ObservedAt, interval)
+		if acc == nil || !acc.start.Equal(start) {
+			if acc != nil {
+				buckets = append(buckets, acc.bucket())
+			}
+			acc = newBucketAccumulator(start, nextInterval(start, interval))
+		}
+		acc.add(obs)
+	}
+	if acc != nil {
+		buckets = append(buckets, acc.bucket())
+	}
+
+	return buckets, nil
+}
+
+// ValidInterval reports whether interval is a supported aggregation interval
+func ValidInterval(interval string) bool {
+	switch interval {
+	case IntervalHour, IntervalDay, IntervalMonth:
+		return true
+	}
+	return false
+}
+
+// truncateToInterval returns the UTC start of the interval containing t
+func truncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case IntervalDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	default:
+		return t.Truncate(time.Hour)
+	}
+}
+
+// nextInterval returns the start of the interval following start
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case IntervalDay:
+		return start.AddDate(0, 0, 1)
+	default:
+		return start.Add(time.Hour)
+	}
+}
+
+// bucketAccumulator collects running aggregates for a single bucket
+type bucketAccumulator struct {
+	start, end                          time.Time
+	lastObservedAt                      time.Time
+	count                               int
+	temp, humidity, pressure, windSpeed runningAggregate
+}
+
+func newBucketAccumulator(start, end time.Time) *bucketAccumulator {
+	return &bucketAccumulator{start: start, end: end}
+}
+
+func (a *bucketAccumulator) add(obs models.Observation) {
+	a.count++
+	if obs.ObservedAt.After(a.lastObservedAt) {
+		a.lastObservedAt = obs.ObservedAt
+	}
+	a.temp.add(obs.Temp)
+	a.humidity.add(float64(obs.Humidity))
+	a.pressure.add(float64(obs.Pressure))
+	a.windSpeed.add(obs.WindSpeed)
+}
+
+func (a *bucketAccumulator) bucket() models.HistoryBucket {
+	return models.HistoryBucket{
+		Start:       a.start,
+		End:         a.end,
+		Count:       a.count,
+		Temperature: a.temp.aggregate(),
+		Humidity:    a.humidity.aggregate(),
+		Pressure:    a.pressure.aggregate(),
+		WindSpeed:   a.windSpeed.aggregate(),
+
+		LastObservedAt: a.lastObservedAt,
+	}
+}
+
+// runningAggregate tracks min, max and sum of a measurement
+type runningAggregate struct {
+	min, max, sum float64
+	n             int
+}
+
+func (r *runningAggregate) add(v float64) {
+	if r.n == 0 || v < r.min {
+		r.min = v
+	}
+	if r.n == 0 || v > r.max {
+		r.max = v
+	}
+	r.sum += v
+	r.n++
+}
+
+func (r *runningAggregate) aggregate() models.Aggregate {
+	if r.n == 0 {
+		return models.Aggregate{}
+	}
+	return models.Aggregate{Min: r.min, Max: r.max, Avg: r.sum / float64(r.n)}
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
+)
+
+// updateHistorySize is the number of recent updates kept for stream resumption
+const updateHistorySize = 256
+
+// subscriberBuffer is the number of pending updates queued per subscriber
+const subscriberBuffer = 16
+
+// Topic kinds published by the refresher
+const (
+	TopicWeather  = "weather"
+	TopicForecast = "forecast"
+	TopicAlerts   = "alerts"
+)
+
+// Topic identifies a stream of updates of one kind for one location
+type Topic struct {
+	Kind     string
+	Location models.Location
+}
+
+// WeatherTopic returns the current weather topic for a location
+func WeatherTopic(loc models.Location) Topic {
+	return Topic{Kind: TopicWeather, Location: loc}
+}
+
+// ForecastTopic returns the forecast topic for a location
+func ForecastTopic(loc models.Location) Topic {
+	return Topic{Kind: TopicForecast, Location: loc}
+}
+
+// AlertsTopic returns the weather alerts topic for a location
+func AlertsTopic(loc models.Location) Topic {
+	return Topic{Kind: TopicAlerts, Location: loc}
+}
+
+func (t Topic) key() string {
+	return t.Kind + "|" + t.Location.Key()
+}
+
+// Update represents a change in data published on a topic
+type Update struct {
+	ID    uint64
+	Topic Topic
+	Data  interface{}
+}
+
+// Refresher polls upstream weather for subscribed topics and fans out changes.
+// Each topic is polled by a single goroutine regardless of how many subscribers
+// are interested in it.
+type Refresher struct {
+	weatherService   *WeatherService
+	weatherInterval  time.Duration
+	forecastInterval time.Duration
+
+	mu      sync.Mutex
+	feeds   map[string]*topicFeed
+	seq     uint64
+	history []Update
+	closed  bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// topicFeed tracks the polling state and subscribers of a single topic
+type topicFeed struct {
+	subscribers map[*Subscription]struct{}
+	latest      *Update
+	fingerprint []byte
+	stop        chan struct{}
+}
+
+// Subscription receives updates for a set of topics
+type Subscription struct {
+	refresher *Refresher
+	topics    map[string]Topic
+	updates   chan Update
+	dropped   atomic.Uint64
+	closed    bool
+}
+
+// NewRefresher creates a new refresher polling weather and forecast topics at
+// the given intervals; alerts are polled as often as current weather
+func NewRefresher(weatherService *WeatherService, weatherInterval, forecastInterval time.Duration) *Refresher {
+	return &Refresher{
+		weatherService:   weatherService,
+		weatherInterval:  weatherInterval,
+		forecastInterval: forecastInterval,
+		feeds:            make(map[string]*topicFeed),
+		done:             make(chan struct{}),
+	}
+}
+
+// Subscribe registers interest in the given topics and starts polling any
+// topic that is not already being polled
+func (r *Refresher) Subscribe(topics []Topic) *Subscription {
+	sub := &Subscription{
+		refresher: r,
+		topics:    make(map[string]Topic),
+		updates:   make(chan Update, subscriberBuffer),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, topic := range topics {
+		r.attach(sub, topic)
+	}
+	return sub
+}
+
+// Since returns the retained updates for the given topics newer than id. It
+// reports false when the client cannot resume from id: either updates after id
+// have already fallen out of the history, or id was issued before a restart.
+func (r *Refresher) Since(id uint64, topics []Topic) ([]Update, bool) {
+	wanted := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		wanted[topic.key()] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if id > r.seq || (len(r.history) > 0 && r.history[0].ID > id+1) {
+		return nil, false
+	}
+
+	var updates []Update
+	for _, update := range r.history {
+		if update.ID > id && wanted[update.Topic.key()] {
+			updates = append(updates, update)
+		}
+	}
+	return updates, true
+}
+
+// Latest returns the most recent update published on a topic, if any
+func (r *Refresher) Latest(topic Topic) (Update, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if feed, ok := r.feeds[topic.key()]; ok && feed.latest != nil {
+		return *feed.latest, true
+	}
+	return Update{}, false
+}
+
+// Done returns a channel that is closed when the refresher shuts down
+func (r *Refresher) Done() <-chan struct{} {
+	return r.done
+}
+
+// Close stops all polling goroutines and waits for them to exit
+func (r *Refresher) Close() {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.closed = true
+	close(r.done)
+	r.mu.Unlock()
+
+	r.wg.Wait()
+}
+
+// attach adds a subscriber to a topic feed; callers must hold r.mu
+func (r *Refresher) attach(sub *Subscription, topic Topic) {
+	key := topic.key()
+	if _, ok := sub.topics[key]; ok || sub.closed {
+		return
+	}
+	sub.topics[key] = topic
+	if r.closed {
+		return
+	}
+
+	feed, ok := r.feeds[key]
+	if !ok {
+		feed = &topicFeed{
+			subscribers: make(map[*Subscription]struct{}),
+			stop:        make(chan struct{}),
+		}
+		r.feeds[key] = feed
+		r.wg.Add(1)
+		go r.poll(topic, feed)
+	}
+	feed.subscribers[sub] = struct{}{}
+}
+
+// detach removes a subscriber from a topic feed; callers must hold r.mu
+func (r *Refresher) detach(sub *Subscription, key string) {
+	delete(sub.topics, key)
+
+	feed, ok := r.feeds[key]
+	if !ok {
+		return
+	}
+	delete(feed.subscribers, sub)
+	if len(feed.subscribers) == 0 {
+		close(feed.stop)
+		delete(r.feeds, key)
+	}
+}
+
+// poll fetches a topic until its feed is stopped or the refresher closes
+func (r *Refresher) poll(topic Topic, feed *topicFeed) {
+	defer r.wg.Done()
+
+	ctx, cancel := context.WithCancel(quota.WithPriority(context.Background(), quota.PriorityBackground))
+	defer cancel()
+	go func() {
+		select {
+		case <-feed.stop:
+		case <-r.done:
+		}
+		cancel()
+	}()
+
+	interval := r.weatherInterval
+	if topic.Kind == TopicForecast {
+		interval = r.forecastInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.refresh(ctx, topic, feed)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh fetches the latest data for a topic and publishes it if it changed
+func (r *Refresher) refresh(ctx context.Context, topic Topic, feed *topicFeed) {
+	var data interface{}
+	var err error
+	switch topic.Kind {
+	case TopicForecast:
+		data, err = r.weatherService.RefreshForecast(ctx, topic.Location)
+	case TopicAlerts:
+		data, err = r.weatherService.RefreshAlerts(ctx, topic.Location)
+	default:
+		data, err = r.weatherService.RefreshCurrentWeather(ctx, topic.Location)
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.WarnContext(ctx, "refresher: fetch failed", "kind", topic.Kind, "location", topic.Location.String(), "error", err)
+		}
+		return
+	}
+
+	fingerprint, err := json.Marshal(data)
+	if err != nil {
+		slog.ErrorContext(ctx, "refresher: encode failed", "kind", topic.Kind, "location", topic.Location.String(), "error", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if bytes.Equal(fingerprint, feed.fingerprint) {
+		return
+	}
+
+	r.seq++
+	update := Update{ID: r.seq, Topic: topic, Data: data}
+	feed.fingerprint = fingerprint
+	feed.latest = &update
+
+	r.history = append(r.history, update)
+	if len(r.history) > updateHistorySize {
+		r.history = r.history[len(r.history)-updateHistorySize:]
+	}
+
+	for sub := range feed.subscribers {
+		select {
+		case sub.updates <- update:
+		default:
+			// Slow subscribers skip this update and catch up on the next change
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Updates returns the channel on which updates are delivered
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+// Dropped returns the number of updates skipped because the subscriber fell behind
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Add subscribes to an additional topic
+func (s *Subscription) Add(topic Topic) {
+	r := s.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attach(s, topic)
+}
+
+// Remove unsubscribes from a topic
+func (s *Subscription) Remove(topic Topic) {
+	r := s.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.detach(s, topic.key())
+}
+
+// Snapshot returns the latest known update for each subscribed topic, oldest first
+func (s *Subscription) Snapshot() []Update {
+	r := s.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var updates []Update
+	for key := range s.topics {
+		if feed, ok := r.feeds[key]; ok && feed.latest != nil {
+			updates = append(updates, *feed.latest)
+		}
+	}
+	sort.Slice(updates, func(i, j int) bool { return updates[i].ID < updates[j].ID })
+	return updates
+}
+
+// Close unregisters the subscription and stops polling topics nobody else follows
+func (s *Subscription) Close() {
+	r := s.refresher
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	for key := range s.topics {
+		r.detach(s, key)
+	}
+	s.closed = true
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+)
+
+// newTestWeatherService returns a service backed by a fake upstream that
+// reports temp() as the current temperature of every city
+func newTestWeatherService(t *testing.T, temp func() float64) *WeatherService {
+	t.Helper()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": r.URL.Query().Get("q"),
+			"main": map[string]float64{"temp": temp()},
+		})
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}
+	return NewWeatherService(cfg, nil, nil, nil, nil)
+}
+
+func TestRefresherSince(t *testing.T) {
+	london := WeatherTopic(models.CityLocation("London"))
+	paris := WeatherTopic(models.CityLocation("Paris"))
+
+	r := NewRefresher(nil, time.Hour, time.Hour)
+	r.seq = 13
+	r.history = []Update{
+		{ID: 10, Topic: london},
+		{ID: 11, Topic: paris},
+		{ID: 12, Topic: london},
+		{ID: 13, Topic: london},
+	}
+
+	tests := []struct {
+		name    string
+		id      uint64
+		wantIDs []uint64
+		wantOK  bool
+	}{
+		{"just before history", 9, []uint64{10, 12, 13}, true},
+		{"within history", 11, []uint64{12, 13}, true},
+		{"up to date", 13, nil, true},
+		{"older than history", 8, nil, false},
+		{"ahead of sequence", 14, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates, ok := r.Since(tt.id, []Topic{london})
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if len(updates) != len(tt.wantIDs) {
+				t.Fatalf("got %d updates, want %d", len(updates), len(tt.wantIDs))
+			}
+			for i, update := range updates {
+				if update.ID != tt.wantIDs[i] {
+					t.Errorf("updates[%d].ID = %d, want %d", i, update.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionSnapshotOrdersByID(t *testing.T) {
+	r := NewRefresher(nil, time.Hour, time.Hour)
+	sub := &Subscription{refresher: r, topics: make(map[string]Topic)}
+
+	cities := []string{"London", "Paris", "Berlin", "Madrid", "Rome", "Oslo", "Vienna", "Prague"}
+	for i, city := range cities {
+		topic := WeatherTopic(models.CityLocation(city))
+		sub.topics[topic.key()] = topic
+		r.feeds[topic.key()] = &topicFeed{latest: &Update{ID: uint64(len(cities) - i), Topic: topic}}
+	}
+
+	snapshot := sub.Snapshot()
+	if len(snapshot) != len(cities) {
+		t.Fatalf("got %d updates, want %d", len(snapshot), len(cities))
+	}
+	for i, update := range snapshot {
+		if update.ID != uint64(i+1) {
+			t.Errorf("snapshot[%d].ID = %d, want %d", i, update.ID, i+1)
+		}
+	}
+}
+
+func TestRefresherPublishesChanges(t *testing.T) {
+	var temp atomic.Int64
+	temp.Store(20)
+	ws := newTestWeatherService(t, func() float64 { return float64(temp.Load()) })
+
+	r := NewRefresher(ws, 10*time.Millisecond, time.Hour)
+	defer r.Close()
+
+	topic := WeatherTopic(models.CityLocation("London"))
+	sub := r.Subscribe([]Topic{topic})
+	defer sub.Close()
+
+	next := func() Update {
+		t.Helper()
+		select {
+		case update := <-sub.Updates():
+			return update
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for update")
+			return Update{}
+		}
+	}
+
+	first := next()
+	if first.ID != 1 || first.Topic != topic {
+		t.Fatalf("first update = %d %v, want 1 %v", first.ID, first.Topic, topic)
+	}
+
+	// Unchanged data is polled again but not republished
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case update := <-sub.Updates():
+		t.Fatalf("unexpected update %d for unchanged data", update.ID)
+	default:
+	}
+
+	temp.Store(25)
+	second := next()
+	if second.ID != 2 {
+		t.Fatalf("second update ID = %d, want 2", second.ID)
+	}
+	if data := second.Data.(*models.WeatherData); data.Main.Temp != 25 {
+		t.Errorf("temp = %v, want 25", data.Main.Temp)
+	}
+	if latest, ok := r.Latest(topic); !ok || latest.ID != 2 {
+		t.Errorf("Latest = %d %v, want 2 true", latest.ID, ok)
+	}
+
+	r.Close()
+	select {
+	case <-r.Done():
+	default:
+		t.Error("Done not closed after Close")
+	}
+}
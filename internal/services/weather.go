@@ -1,79 +1,602 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	neturl "net/url"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/cache"
 	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/metrics"
 	"github.com/ANAS727189/weather-project/internal/models"
+	"github.com/ANAS727189/weather-project/internal/quota"
+	"github.com/ANAS727189/weather-project/internal/storage"
+	"github.com/ANAS727189/weather-project/internal/tracing"
+	"github.com/ANAS727189/weather-project/internal/utils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // WeatherService handles weather-related operations
 type WeatherService struct {
-	config     *config.Config
+	config     atomic.Pointer[config.Config]
 	httpClient *http.Client
+	cache      cache.Backend
+	store      storage.Store
+	budget     *quota.Budget
+	breaker    *breaker.Breaker
 }
 
-// NewWeatherService creates a new weather service instance
-func NewWeatherService(cfg *config.Config) *WeatherService {
-	return &WeatherService{
-		config: cfg,
+// ErrAlertsUnavailable is returned for alert requests when no alerts endpoint is configured
+var ErrAlertsUnavailable = errors.New("weather alerts are not configured")
+
+// unavailable reports whether err means upstream may not be called right now,
+// in which case a stale cached response is better than none
+func unavailable(err error) bool {
+	return errors.Is(err, quota.ErrBudgetExhausted) || errors.Is(err, breaker.ErrOpen)
+}
+
+// retryBackoff is the wait before the first upstream retry; it doubles for each further retry
+var retryBackoff = 250 * time.Millisecond
+
+// coalescePoll is how often a request waiting on another caller's refresh
+// checks whether the response has been cached
+const coalescePoll = 100 * time.Millisecond
+
+// NewWeatherService creates a new weather service instance.
+// Responses are cached in backend, observations are recorded to store,
+// upstream calls are charged to budget and guarded by breaker when they are
+// not nil.
+func NewWeatherService(cfg *config.Config, backend cache.Backend, store storage.Store, budget *quota.Budget, b *breaker.Breaker) *WeatherService {
+	ws := &WeatherService{
 		httpClient: &http.Client{
 			Timeout: time.Duration(cfg.API.Timeout) * time.Second,
 		},
+		cache:   backend,
+		store:   store,
+		budget:  budget,
+		breaker: b,
 	}
+	ws.config.Store(cfg)
+	return ws
+}
+
+// SetConfig swaps in configuration for subsequent requests, such as a new
+// upstream API key or cache TTLs. Settings fixed at construction, like the
+// HTTP timeout and whether caching is enabled, are not affected.
+func (ws *WeatherService) SetConfig(cfg *config.Config) {
+	ws.config.Store(cfg)
 }
 
 // GetCurrentWeather fetches current weather data for a city
-func (ws *WeatherService) GetCurrentWeather(city string) (*models.WeatherData, error) {
-	url := fmt.Sprintf("%s/weather?q=%s&appid=%s&units=metric",
-		ws.config.API.BaseURL, city, ws.config.API.OpenWeatherMapApiKey)
+func (ws *WeatherService) GetCurrentWeather(ctx context.Context, city string) (*models.WeatherData, error) {
+	return ws.GetCurrentWeatherAt(ctx, models.CityLocation(city))
+}
 
-	resp, err := ws.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch weather data: %v", err)
+// GetCurrentWeatherByCoords fetches current weather data for a coordinate pair
+func (ws *WeatherService) GetCurrentWeatherByCoords(ctx context.Context, lat, lon float64) (*models.WeatherData, error) {
+	return ws.GetCurrentWeatherAt(ctx, models.CoordLocation(lat, lon))
+}
+
+// GetCurrentWeatherAt returns current weather for a location, served from cache when fresh
+func (ws *WeatherService) GetCurrentWeatherAt(ctx context.Context, loc models.Location) (*models.WeatherData, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WeatherService.GetCurrentWeather", trace.WithAttributes(locationAttributes(loc)...))
+	defer span.End()
+
+	if data, ok := ws.cached(ctx, TopicWeather, loc); ok {
+		return data.(*models.WeatherData), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("city not found")
+	data, err := ws.refreshCoalesced(ctx, TopicWeather, loc, func() (interface{}, error) {
+		return ws.RefreshCurrentWeather(ctx, loc)
+	})
+	if unavailable(err) {
+		if stale, ok := ws.stale(ctx, TopicWeather, loc); ok {
+			return stale.(*models.WeatherData), nil
+		}
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %s", resp.Status)
+	if err != nil {
+		return nil, err
 	}
+	return data.(*models.WeatherData), nil
+}
 
+// RefreshCurrentWeather fetches current weather upstream, bypassing and then updating the cache
+func (ws *WeatherService) RefreshCurrentWeather(ctx context.Context, loc models.Location) (*models.WeatherData, error) {
 	var data models.WeatherData
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to decode weather data: %v", err)
+	if err := ws.fetch(ctx, "weather", loc, &data); err != nil {
+		return nil, err
 	}
 
+	data.FetchedAt = time.Now()
+	data.ExpiresAt = ws.setCached(ctx, TopicWeather, loc, &data, time.Duration(ws.config.Load().Cache.WeatherTTL)*time.Second)
+	ws.record(ctx, loc, &data)
 	return &data, nil
 }
 
 // GetForecast fetches forecast data for a city
-func (ws *WeatherService) GetForecast(city string) (*models.ForecastData, error) {
-	url := fmt.Sprintf("%s/forecast?q=%s&appid=%s&units=metric",
-		ws.config.API.BaseURL, city, ws.config.API.OpenWeatherMapApiKey)
+func (ws *WeatherService) GetForecast(ctx context.Context, city string) (*models.ForecastData, error) {
+	return ws.GetForecastAt(ctx, models.CityLocation(city))
+}
 
-	resp, err := ws.httpClient.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch forecast data: %v", err)
+// GetForecastByCoords fetches forecast data for a coordinate pair
+func (ws *WeatherService) GetForecastByCoords(ctx context.Context, lat, lon float64) (*models.ForecastData, error) {
+	return ws.GetForecastAt(ctx, models.CoordLocation(lat, lon))
+}
+
+// GetForecastAt returns forecast data for a location, served from cache when fresh
+func (ws *WeatherService) GetForecastAt(ctx context.Context, loc models.Location) (*models.ForecastData, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WeatherService.GetForecast", trace.WithAttributes(locationAttributes(loc)...))
+	defer span.End()
+
+	if data, ok := ws.cached(ctx, TopicForecast, loc); ok {
+		return data.(*models.ForecastData), nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("city not found")
+	data, err := ws.refreshCoalesced(ctx, TopicForecast, loc, func() (interface{}, error) {
+		return ws.RefreshForecast(ctx, loc)
+	})
+	if unavailable(err) {
+		if stale, ok := ws.stale(ctx, TopicForecast, loc); ok {
+			return stale.(*models.ForecastData), nil
+		}
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API request failed with status: %s", resp.Status)
+	if err != nil {
+		return nil, err
 	}
+	return data.(*models.ForecastData), nil
+}
 
+// RefreshForecast fetches forecast data upstream, bypassing and then updating the cache
+func (ws *WeatherService) RefreshForecast(ctx context.Context, loc models.Location) (*models.ForecastData, error) {
 	var data models.ForecastData
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, fmt.Errorf("failed to decode forecast data: %v", err)
+	if err := ws.fetch(ctx, "forecast", loc, &data); err != nil {
+		return nil, err
 	}
 
+	data.FetchedAt = time.Now()
+	data.ExpiresAt = ws.setCached(ctx, TopicForecast, loc, &data, time.Duration(ws.config.Load().Cache.ForecastTTL)*time.Second)
 	return &data, nil
 }
+
+// GetAlerts fetches the weather alerts in force for a city
+func (ws *WeatherService) GetAlerts(ctx context.Context, city string) (*models.AlertsData, error) {
+	return ws.GetAlertsAt(ctx, models.CityLocation(city))
+}
+
+// GetAlertsAt returns the weather alerts for a location, served from cache when fresh
+func (ws *WeatherService) GetAlertsAt(ctx context.Context, loc models.Location) (*models.AlertsData, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "WeatherService.GetAlerts", trace.WithAttributes(locationAttributes(loc)...))
+	defer span.End()
+
+	if data, ok := ws.cached(ctx, TopicAlerts, loc); ok {
+		return data.(*models.AlertsData), nil
+	}
+
+	data, err := ws.refreshCoalesced(ctx, TopicAlerts, loc, func() (interface{}, error) {
+		return ws.RefreshAlerts(ctx, loc)
+	})
+	if unavailable(err) {
+		if stale, ok := ws.stale(ctx, TopicAlerts, loc); ok {
+			return stale.(*models.AlertsData), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data.(*models.AlertsData), nil
+}
+
+// RefreshAlerts fetches weather alerts upstream, bypassing and then updating
+// the cache. The One Call API only accepts coordinates, so a city is first
+// resolved through its current weather, which is usually cached.
+func (ws *WeatherService) RefreshAlerts(ctx context.Context, loc models.Location) (*models.AlertsData, error) {
+	api := ws.config.Load().API
+	if api.AlertsURL == "" {
+		return nil, ErrAlertsUnavailable
+	}
+
+	lat, lon := loc.Lat, loc.Lon
+	if !loc.ByCoords {
+		weather, err := ws.GetCurrentWeatherAt(ctx, loc)
+		if err != nil {
+			return nil, err
+		}
+		lat, lon = weather.Coord.Lat, weather.Coord.Lon
+	}
+
+	url := fmt.Sprintf("%s?lat=%f&lon=%f&exclude=current,minutely,hourly,daily&appid=%s&units=metric",
+		api.AlertsURL, lat, lon, api.OpenWeatherMapApiKey)
+	var data models.AlertsData
+	if err := ws.call(ctx, "onecall", url, loc, &data); err != nil {
+		return nil, err
+	}
+	if data.Alerts == nil {
+		data.Alerts = []models.WeatherAlert{}
+	}
+
+	data.FetchedAt = time.Now()
+	data.ExpiresAt = ws.setCached(ctx, TopicAlerts, loc, &data, time.Duration(ws.config.Load().Cache.WeatherTTL)*time.Second)
+	return &data, nil
+}
+
+// fetch calls an upstream endpoint for a location and decodes the JSON response into out
+func (ws *WeatherService) fetch(ctx context.Context, endpoint string, loc models.Location, out interface{}) error {
+	api := ws.config.Load().API
+	var url string
+	if loc.ByCoords {
+		url = fmt.Sprintf("%s/%s?lat=%f&lon=%f&appid=%s&units=metric",
+			api.BaseURL, endpoint, loc.Lat, loc.Lon, api.OpenWeatherMapApiKey)
+	} else {
+		url = fmt.Sprintf("%s/%s?q=%s&appid=%s&units=metric",
+			api.BaseURL, endpoint, loc.City, api.OpenWeatherMapApiKey)
+	}
+	return ws.call(ctx, endpoint, url, loc, out)
+}
+
+// call requests url, an upstream endpoint queried for loc, and decodes the JSON
+// response into out. Transport errors and 5xx answers are retried up to
+// api.max_retries times with exponential backoff. Each attempt must pass the
+// circuit breaker and is charged to the budget; a retry that either refuses
+// reports the last failure instead. Transport errors and 5xx answers count as
+// breaker failures, and any other answer shows upstream is healthy.
+func (ws *WeatherService) call(ctx context.Context, endpoint, url string, loc models.Location, out interface{}) error {
+	ctx, span := tracing.Tracer().Start(ctx, "openweathermap "+endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(locationAttributes(loc), attribute.String("weather.provider", "openweathermap"))...),
+	)
+	defer span.End()
+
+	maxRetries := ws.config.Load().API.MaxRetries
+	var failure error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				span.SetStatus(codes.Error, "upstream request failed")
+				return failure
+			case <-time.After(retryBackoff << (attempt - 1)):
+			}
+		}
+
+		if ws.breaker != nil {
+			if err := ws.breaker.Allow(); err != nil {
+				if failure != nil {
+					span.SetStatus(codes.Error, "upstream request failed")
+					return failure
+				}
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+		}
+		if ws.budget != nil {
+			if err := ws.budget.Acquire(quota.PriorityFromContext(ctx)); err != nil {
+				if failure != nil {
+					span.SetStatus(codes.Error, "upstream request failed")
+					return failure
+				}
+				span.SetStatus(codes.Error, err.Error())
+				return err
+			}
+		}
+		if attempt > 0 {
+			metrics.UpstreamRetries.WithLabelValues(endpoint).Inc()
+			span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt)))
+		}
+
+		start := time.Now()
+		resp, err := ws.get(ctx, url)
+		if err != nil {
+			// The request URL carries the API key, so mask it before the error is logged or returned
+			var urlErr *neturl.Error
+			if errors.As(err, &urlErr) {
+				urlErr.URL = utils.RedactURL(urlErr.URL)
+			}
+			metrics.UpstreamDuration.WithLabelValues(endpoint, "error").Observe(time.Since(start).Seconds())
+			slog.WarnContext(ctx, "upstream request failed", "endpoint", endpoint, "location", loc.String(), "attempt", attempt+1, "duration", time.Since(start), "error", err)
+			span.RecordError(err)
+			// A call cancelled by its own caller says nothing about upstream health
+			if ctx.Err() == nil {
+				ws.recordUpstream(true)
+			}
+			failure = fmt.Errorf("failed to fetch %s data: %v", endpoint, err)
+			if attempt < maxRetries && ctx.Err() == nil {
+				continue
+			}
+			span.SetStatus(codes.Error, "upstream request failed")
+			return failure
+		}
+		metrics.UpstreamDuration.WithLabelValues(endpoint, strconv.Itoa(resp.StatusCode)).Observe(time.Since(start).Seconds())
+		slog.DebugContext(ctx, "upstream request", "url", utils.RedactURL(url), "status", resp.StatusCode, "attempt", attempt+1, "duration", time.Since(start))
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		ws.recordUpstream(resp.StatusCode >= http.StatusInternalServerError)
+
+		if resp.StatusCode >= http.StatusInternalServerError && attempt < maxRetries {
+			resp.Body.Close()
+			failure = fmt.Errorf("API request failed with status: %s", resp.Status)
+			continue
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("city not found")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("API request failed with status: %s", resp.Status)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode %s data: %v", endpoint, err)
+		}
+		return nil
+	}
+}
+
+// recordUpstream tells the breaker whether a call that reached upstream failed
+func (ws *WeatherService) recordUpstream(failed bool) {
+	switch {
+	case ws.breaker == nil:
+	case failed:
+		ws.breaker.Failure()
+	default:
+		ws.breaker.Success()
+	}
+}
+
+// cached returns a fresh cached response for a topic kind and location
+func (ws *WeatherService) cached(ctx context.Context, kind string, loc models.Location) (interface{}, bool) {
+	if ws.cache == nil {
+		return nil, false
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "cache.get", trace.WithAttributes(attribute.String("cache.kind", kind)))
+	defer span.End()
+
+	data, _, ok := ws.lookup(ctx, kind, loc, false)
+	span.SetAttributes(attribute.Bool("cache.hit", ok))
+	if ok {
+		metrics.CacheLookups.WithLabelValues(kind, "hit").Inc()
+	} else {
+		metrics.CacheLookups.WithLabelValues(kind, "miss").Inc()
+	}
+	return data, ok
+}
+
+// stale returns an expired cached response still within the stale window
+func (ws *WeatherService) stale(ctx context.Context, kind string, loc models.Location) (interface{}, bool) {
+	if ws.cache == nil {
+		return nil, false
+	}
+	data, entry, ok := ws.lookup(ctx, kind, loc, true)
+	if ok {
+		metrics.CacheLookups.WithLabelValues(kind, "stale").Inc()
+		slog.WarnContext(ctx, "upstream budget exhausted, serving stale cache", "kind", kind, "location", loc.String(), "age", time.Since(entry.StoredAt).Round(time.Second))
+	}
+	return data, ok
+}
+
+// lookup reads and decodes the cached response for a topic kind and location,
+// including expired entries when allowStale is set. Backend errors and
+// undecodable entries are logged and treated as misses so a cache outage
+// falls back to upstream instead of failing requests.
+func (ws *WeatherService) lookup(ctx context.Context, kind string, loc models.Location, allowStale bool) (interface{}, cache.Entry, bool) {
+	key := kind + "|" + loc.Key()
+	entry, ok, err := ws.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "cache read failed", "key", key, "error", err)
+		return nil, cache.Entry{}, false
+	}
+	if !ok || (!allowStale && entry.Expired(time.Now())) {
+		return nil, cache.Entry{}, false
+	}
+
+	var data interface{}
+	switch kind {
+	case TopicForecast:
+		forecast := &models.ForecastData{FetchedAt: entry.StoredAt, ExpiresAt: entry.ExpiresAt}
+		data, err = forecast, json.Unmarshal(entry.Value, forecast)
+	case TopicAlerts:
+		alerts := &models.AlertsData{FetchedAt: entry.StoredAt, ExpiresAt: entry.ExpiresAt}
+		data, err = alerts, json.Unmarshal(entry.Value, alerts)
+	default:
+		weather := &models.WeatherData{FetchedAt: entry.StoredAt, ExpiresAt: entry.ExpiresAt}
+		data, err = weather, json.Unmarshal(entry.Value, weather)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "discarding undecodable cache entry", "key", key, "error", err)
+		return nil, cache.Entry{}, false
+	}
+	return data, entry, true
+}
+
+// refreshCoalesced calls refresh for a cache miss while holding the backend
+// lock for the response, so concurrent misses for the same location make a
+// single upstream call even across instances sharing a cache. Callers that
+// find the lock taken wait for the holder to store the response, and take
+// over if the holder releases the lock without storing one.
+func (ws *WeatherService) refreshCoalesced(ctx context.Context, kind string, loc models.Location, refresh func() (interface{}, error)) (interface{}, error) {
+	if ws.cache == nil {
+		return refresh()
+	}
+
+	// The lock outlives every attempt and backoff so it only expires if its holder died
+	key := kind + "|" + loc.Key()
+	api := ws.config.Load().API
+	lockTTL := time.Duration(api.MaxRetries+1)*time.Duration(api.Timeout)*time.Second + retryBackoff<<api.MaxRetries + 5*time.Second
+	ticker := time.NewTicker(coalescePoll)
+	defer ticker.Stop()
+	for {
+		unlock, ok, err := ws.cache.Lock(ctx, key, lockTTL)
+		if err != nil {
+			slog.WarnContext(ctx, "cache lock failed, refreshing without coalescing", "key", key, "error", err)
+			return refresh()
+		}
+		if ok {
+			defer unlock()
+			return refresh()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if data, _, ok := ws.lookup(ctx, kind, loc, false); ok {
+			metrics.CacheLookups.WithLabelValues(kind, "coalesced").Inc()
+			return data, nil
+		}
+	}
+}
+
+// ProbeUpstream checks that OpenWeatherMap answers requests for city with the
+// configured API key. A "city not found" answer still proves the API is
+// reachable, and an exhausted budget is not treated as a failure because
+// cached responses can still be served.
+func (ws *WeatherService) ProbeUpstream(ctx context.Context, city string) error {
+	ctx = quota.WithPriority(ctx, quota.PriorityBackground)
+
+	var data models.WeatherData
+	err := ws.fetch(ctx, "weather", models.CityLocation(city), &data)
+	if err == nil || errors.Is(err, quota.ErrBudgetExhausted) || err.Error() == "city not found" {
+		return nil
+	}
+	return err
+}
+
+// PingCache checks that the response cache backend is reachable
+func (ws *WeatherService) PingCache(ctx context.Context) error {
+	if ws.cache == nil {
+		return nil
+	}
+	return ws.cache.Ping(ctx)
+}
+
+// RetryAfter returns how long until the upstream budget allows calls again
+func (ws *WeatherService) RetryAfter() time.Duration {
+	if ws.budget == nil {
+		return 0
+	}
+	return ws.budget.RetryAfter()
+}
+
+// BreakerRetryAfter returns how long until the open circuit breaker lets a trial call through
+func (ws *WeatherService) BreakerRetryAfter() time.Duration {
+	if ws.breaker == nil {
+		return 0
+	}
+	return ws.breaker.RetryAfter()
+}
+
+// BreakerStatus returns the circuit breaker state, or false when no breaker is configured
+func (ws *WeatherService) BreakerStatus() (breaker.Status, bool) {
+	if ws.breaker == nil {
+		return breaker.Status{}, false
+	}
+	return ws.breaker.Status(), true
+}
+
+// ErrBreakerDisabled is returned by breaker controls when no circuit breaker is configured
+var ErrBreakerDisabled = errors.New("circuit breaker is not enabled")
+
+// ForceBreaker pins the circuit breaker open or closed until ResetBreaker
+func (ws *WeatherService) ForceBreaker(state string) (breaker.Status, error) {
+	if ws.breaker == nil {
+		return breaker.Status{}, ErrBreakerDisabled
+	}
+	if err := ws.breaker.Force(state); err != nil {
+		return breaker.Status{}, err
+	}
+	return ws.breaker.Status(), nil
+}
+
+// ResetBreaker closes the circuit breaker and returns it to automatic operation
+func (ws *WeatherService) ResetBreaker() (breaker.Status, error) {
+	if ws.breaker == nil {
+		return breaker.Status{}, ErrBreakerDisabled
+	}
+	ws.breaker.Reset()
+	return ws.breaker.Status(), nil
+}
+
+// BudgetStatus returns upstream budget usage, or false when no budget is configured
+func (ws *WeatherService) BudgetStatus() (quota.Status, bool) {
+	if ws.budget == nil {
+		return quota.Status{}, false
+	}
+	return ws.budget.Status(), true
+}
+
+// setCached stores a response for a topic kind and location and returns when
+// it expires, or the zero time if it was not cached. Failures are logged
+// rather than returned since the response itself was fetched fine.
+func (ws *WeatherService) setCached(ctx context.Context, kind string, loc models.Location, data interface{}, ttl time.Duration) time.Time {
+	if ws.cache == nil || ttl <= 0 {
+		return time.Time{}
+	}
+	key := kind + "|" + loc.Key()
+	value, err := json.Marshal(data)
+	if err == nil {
+		err = ws.cache.Set(ctx, key, value, ttl)
+	}
+	if err != nil {
+		slog.WarnContext(ctx, "cache write failed", "key", key, "error", err)
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// record stores an observation when history storage is enabled; failures are
+// logged rather than returned so storage problems never fail a weather request
+func (ws *WeatherService) record(ctx context.Context, loc models.Location, data *models.WeatherData) {
+	if ws.store == nil {
+		return
+	}
+	obs := models.NewObservation(loc, data, time.Now())
+	if err := ws.store.Record(ctx, obs); err != nil {
+		slog.ErrorContext(ctx, "failed to record observation", "location", loc.String(), "error", err)
+	}
+}
+
+// get issues a GET request to the upstream API bound to the given context.
+// Its trace context is sent in the request headers only when upstream
+// propagation is configured.
+func (ws *WeatherService) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ws.config.Load().Tracing.PropagateUpstream {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+	)
+	return ws.httpClient.Do(req)
+}
+
+// locationAttributes describes a location on a span
+func locationAttributes(loc models.Location) []attribute.KeyValue {
+	if loc.ByCoords {
+		return []attribute.KeyValue{
+			attribute.Float64("weather.lat", loc.Lat),
+			attribute.Float64("weather.lon", loc.Lon),
+		}
+	}
+	return []attribute.KeyValue{attribute.String("weather.city", loc.City)}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/breaker"
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/metrics"
+	"github.com/ANAS727189/weather-project/internal/quota"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestFetchRetries(t *testing.T) {
+	defer func(backoff time.Duration) { retryBackoff = backoff }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	tests := []struct {
+		name        string
+		statuses    []int
+		maxRetries  int
+		budget      *quota.Budget
+		wantErr     string
+		wantCalls   int64
+		wantRetries float64
+	}{
+		{"recovers after 5xx", []int{503, 502, 200}, 2, nil, "", 3, 2},
+		{"gives up after max retries", []int{503, 503, 503, 200}, 2, nil, "503", 3, 2},
+		{"retries disabled", []int{500, 200}, 0, nil, "500", 1, 0},
+		{"client errors are not retried", []int{404, 200}, 2, nil, "city not found", 1, 0},
+		{"budget does not cover the retry", []int{503, 200}, 2, quota.NewBudget(1, 100, 0), "503", 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int64
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[calls.Add(1)-1]
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"name": "London"})
+			}))
+			defer upstream.Close()
+
+			cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5, MaxRetries: tt.maxRetries}}
+			ws := NewWeatherService(cfg, nil, nil, tt.budget, nil)
+			retries := metrics.UpstreamRetries.WithLabelValues("weather")
+			before := testutil.ToFloat64(retries)
+
+			_, err := ws.GetCurrentWeather(context.Background(), "London")
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("GetCurrentWeather: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("err = %v, want one mentioning %q", err, tt.wantErr)
+			case errors.Is(err, quota.ErrBudgetExhausted):
+				t.Fatalf("err = %v, want the upstream failure rather than the budget", err)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("upstream calls = %d, want %d", got, tt.wantCalls)
+			}
+			if got := testutil.ToFloat64(retries) - before; got != tt.wantRetries {
+				t.Errorf("retries recorded = %v, want %v", got, tt.wantRetries)
+			}
+		})
+	}
+}
+
+func TestFetchOpensBreaker(t *testing.T) {
+	var calls atomic.Int64
+	status := http.StatusServiceUnavailable
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "London"})
+	}))
+	defer upstream.Close()
+
+	b := breaker.New(2, time.Minute)
+	cfg := &config.Config{API: config.APIConfig{BaseURL: upstream.URL, Timeout: 5}}
+	ws := NewWeatherService(cfg, nil, nil, nil, b)
+
+	for i := 0; i < 2; i++ {
+		if _, err := ws.GetCurrentWeather(context.Background(), "London"); err == nil || errors.Is(err, breaker.ErrOpen) {
+			t.Fatalf("call %d: err = %v, want the upstream failure", i+1, err)
+		}
+	}
+	status = http.StatusOK
+	if _, err := ws.GetCurrentWeather(context.Background(), "London"); !errors.Is(err, breaker.ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen once the threshold is reached", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("upstream calls = %d, want 2", got)
+	}
+
+	// Answers other than 5xx show upstream is healthy and close the breaker
+	b.Reset()
+	status = http.StatusNotFound
+	ws.GetCurrentWeather(context.Background(), "Atlantis")
+	ws.GetCurrentWeather(context.Background(), "Atlantis")
+	if got := b.Status(); got.State != breaker.StateClosed || got.Failures != 0 {
+		t.Errorf("breaker after 404s = %+v, want closed", got)
+	}
+}
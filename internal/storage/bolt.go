@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket         = []byte("meta")
+	observationsBucket = []byte("observations")
+	schemaVersionKey   = []byte("schema_version")
+)
+
+// boltMigrations upgrade the schema one version at a time; the slice index
+// plus one is the version a migration produces
+var boltMigrations = []func(tx *bolt.Tx) error{
+	// v1: one nested bucket of time-keyed observations per location
+	func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(observationsBucket)
+		return err
+	},
+}
+
+func init() {
+	Register("bolt", openBoltStore)
+}
+
+// BoltStore stores observations in an embedded bbolt database
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func openBoltStore(cfg config.StorageConfig) (Store, error) {
+	return OpenBoltStore(cfg.Path)
+}
+
+// OpenBoltStore opens the database at path, creating it and migrating its schema as needed
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt database %s: %v", path, err)
+	}
+
+	store := &BoltStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate bolt database %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// migrate applies any migrations newer than the stored schema version
+func (s *BoltStore) migrate() error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		var version uint64
+		if raw := meta.Get(schemaVersionKey); raw != nil {
+			version = binary.BigEndian.Uint64(raw)
+		}
+		if version > uint64(len(boltMigrations)) {
+			return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(boltMigrations))
+		}
+
+		for ; version < uint64(len(boltMigrations)); version++ {
+			if err := boltMigrations[version](tx); err != nil {
+				return fmt.Errorf("migration to version %d failed: %v", version+1, err)
+			}
+		}
+		return meta.Put(schemaVersionKey, encodeUint64(version))
+	})
+}
+
+// Record saves an observation keyed by location and observation time
+func (s *BoltStore) Record(ctx context.Context, obs models.Observation) error {
+	value, err := json.Marshal(obs)
+	if err != nil {
+		return fmt.Errorf("failed to encode observation: %v", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		location, err := tx.Bucket(observationsBucket).CreateBucketIfNotExists([]byte(obs.Location))
+		if err != nil {
+			return err
+		}
+		return location.Put(timeKey(obs.ObservedAt), value)
+	})
+}
+
+// Query returns observations for a location observed within [from, to), oldest first
+func (s *BoltStore) Query(ctx context.Context, location string, from, to time.Time) ([]models.Observation, error) {
+	var observations []models.Observation
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(observationsBucket).Bucket([]byte(location))
+		if bucket == nil {
+			return nil
+		}
+
+		end := timeKey(to)
+		c := bucket.Cursor()
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var obs models.Observation
+			if err := json.Unmarshal(v, &obs); err != nil {
+				return fmt.Errorf("failed to decode observation: %v", err)
+			}
+			observations = append(observations, obs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return observations, nil
+}
+
+// Prune deletes observations observed before the given time
+func (s *BoltStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	removed := 0
+	cutoff := timeKey(before)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(observationsBucket).ForEachBucket(func(name []byte) error {
+			c := tx.Bucket(observationsBucket).Bucket(name).Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, cutoff) < 0; k, _ = c.First() {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				if err := c.Delete(); err != nil {
+					return err
+				}
+				removed++
+			}
+			return nil
+		})
+	})
+	return removed, err
+}
+
+// Ping reads the schema version to confirm the database is open and readable
+func (s *BoltStore) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(metaBucket) == nil {
+			return fmt.Errorf("meta bucket is missing")
+		}
+		return nil
+	})
+}
+
+// Close closes the database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// timeKey encodes a time as a big-endian key so keys sort chronologically
+func timeKey(t time.Time) []byte {
+	return encodeUint64(uint64(t.Unix()))
+}
+
+func encodeUint64(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestBoltStore opens a bolt store in a temporary directory
+func newTestBoltStore(t *testing.T) *BoltStore {
+	t.Helper()
+
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "history.db"))
+	if err != nil {
+		t.Fatalf("OpenBoltStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// writeRawBoltFile creates a database at path whose meta bucket records the
+// given schema version and nothing else
+func writeRawBoltFile(t *testing.T, path string, version uint64) {
+	t.Helper()
+
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open: %v", err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucket(metaBucket)
+		if err != nil {
+			return err
+		}
+		return meta.Put(schemaVersionKey, encodeUint64(version))
+	})
+	if err != nil {
+		t.Fatalf("write schema version: %v", err)
+	}
+}
+
+func TestOpenBoltStoreMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	writeRawBoltFile(t, path, 0)
+
+	store, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore: %v", err)
+	}
+	defer store.Close()
+
+	err = store.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(observationsBucket) == nil {
+			t.Error("observations bucket was not created")
+		}
+		if got := binary.BigEndian.Uint64(tx.Bucket(metaBucket).Get(schemaVersionKey)); got != uint64(len(boltMigrations)) {
+			t.Errorf("schema version = %d, want %d", got, len(boltMigrations))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("view: %v", err)
+	}
+
+	// Migrated databases keep working after a reopen
+	if err := store.Record(context.Background(), models.Observation{Location: "london", ObservedAt: time.Unix(1000, 0)}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	store.Close()
+	reopened, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	reopened.Close()
+}
+
+func TestOpenBoltStoreRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	writeRawBoltFile(t, path, uint64(len(boltMigrations))+1)
+
+	_, err := OpenBoltStore(path)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Fatalf("error = %v, want a newer schema error", err)
+	}
+}
+
+func TestBoltStoreRecordQueryPrune(t *testing.T) {
+	store := newTestBoltStore(t)
+	ctx := context.Background()
+	base := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	for i, temp := range []float64{10, 11, 12, 13} {
+		for _, location := range []string{"london", "paris"} {
+			obs := models.Observation{Location: location, ObservedAt: base.Add(time.Duration(i) * time.Hour), Temp: temp}
+			if err := store.Record(ctx, obs); err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+		}
+	}
+	// Recording the same time again replaces the observation
+	if err := store.Record(ctx, models.Observation{Location: "london", ObservedAt: base.Add(time.Hour), Temp: 21}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	got, err := store.Query(ctx, "london", base.Add(time.Hour), base.Add(3*time.Hour))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(got) != 2 || got[0].Temp != 21 || got[1].Temp != 12 {
+		t.Fatalf("Query = %+v, want temps 21 and 12", got)
+	}
+	if got, _ := store.Query(ctx, "berlin", base, base.Add(24*time.Hour)); len(got) != 0 {
+		t.Errorf("Query for an unknown location returned %d observations", len(got))
+	}
+
+	removed, err := store.Prune(ctx, base.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("Prune: %v", err)
+	}
+	if removed != 4 {
+		t.Errorf("removed %d observations, want 4", removed)
+	}
+	if got, _ := store.Query(ctx, "paris", base, base.Add(24*time.Hour)); len(got) != 2 || !got[0].ObservedAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("remaining observations = %+v", got)
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+)
+
+// Store persists normalized weather observations per location
+type Store interface {
+	// Record saves an observation, replacing any earlier one for the same location and time
+	Record(ctx context.Context, obs models.Observation) error
+
+	// Query returns observations for a location observed within [from, to), oldest first
+	Query(ctx context.Context, location string, from, to time.Time) ([]models.Observation, error)
+
+	// Prune deletes observations observed before the given time and reports how many were removed
+	Prune(ctx context.Context, before time.Time) (int, error)
+
+	// Ping verifies the store can serve reads
+	Ping(ctx context.Context) error
+
+	// Close releases the underlying storage
+	Close() error
+}
+
+func init() {
+	config.RegisterCheck("storage.driver", func(cfg *config.Config) error {
+		if !cfg.Storage.Enabled || cfg.Storage.Driver == "" {
+			return nil
+		}
+		if !slices.Contains(Drivers(), cfg.Storage.Driver) {
+			return fmt.Errorf("unknown driver %q (available: %v)", cfg.Storage.Driver, Drivers())
+		}
+		return nil
+	})
+}
+
+// Factory opens a store from configuration
+type Factory func(cfg config.StorageConfig) (Store, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]Factory)
+)
+
+// Register makes a storage backend available under the given driver name
+func Register(driver string, factory Factory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if _, exists := backends[driver]; exists {
+		panic(fmt.Sprintf("storage: driver %q registered twice", driver))
+	}
+	backends[driver] = factory
+}
+
+// Drivers returns the names of the registered storage backends
+func Drivers() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open opens the store selected by the configured driver
+func Open(cfg config.StorageConfig) (Store, error) {
+	backendsMu.RLock()
+	factory, ok := backends[cfg.Driver]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q (available: %v)", cfg.Driver, Drivers())
+	}
+	return factory(cfg)
+}
+
+// RetentionWorker periodically deletes observations older than the retention period
+type RetentionWorker struct {
+	store     Store
+	retention time.Duration
+	interval  time.Duration
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewRetentionWorker creates a worker pruning the store every interval
+func NewRetentionWorker(store Store, retention, interval time.Duration) *RetentionWorker {
+	return &RetentionWorker{
+		store:     store,
+		retention: retention,
+		interval:  interval,
+		done:      make(chan struct{}),
+	}
+}
+
+// Start begins pruning in the background
+func (w *RetentionWorker) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			w.prune()
+
+			select {
+			case <-w.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop halts pruning and waits for an in-progress run to finish
+func (w *RetentionWorker) Stop() {
+	close(w.done)
+	w.wg.Wait()
+}
+
+func (w *RetentionWorker) prune() {
+	removed, err := w.store.Prune(context.Background(), time.Now().Add(-w.retention))
+	if err != nil {
+		slog.Error("storage: retention pruning failed", "error", err)
+		return
+	}
+	if removed > 0 {
+		slog.Info("storage: pruned observations", "removed", removed, "older_than", w.retention)
+	}
+}
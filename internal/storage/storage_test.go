@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"github.com/ANAS727189/weather-project/internal/models"
+)
+
+func TestDriverRegistry(t *testing.T) {
+	opened := false
+	Register("registry-test", func(cfg config.StorageConfig) (Store, error) {
+		opened = true
+		return nil, nil
+	})
+
+	if drivers := Drivers(); !slices.Contains(drivers, "bolt") || !slices.Contains(drivers, "registry-test") {
+		t.Errorf("Drivers = %v, want bolt and registry-test", drivers)
+	}
+	if _, err := Open(config.StorageConfig{Driver: "registry-test"}); err != nil || !opened {
+		t.Errorf("Open did not use the registered factory: %v", err)
+	}
+	if _, err := Open(config.StorageConfig{Driver: "postgres"}); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a driver twice did not panic")
+		}
+	}()
+	Register("registry-test", nil)
+}
+
+func TestRetentionWorkerPrunes(t *testing.T) {
+	store := newTestBoltStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, age := range []time.Duration{72 * time.Hour, 25 * time.Hour, time.Hour} {
+		if err := store.Record(ctx, models.Observation{Location: "london", ObservedAt: now.Add(-age)}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// The worker prunes as soon as it starts, long before its first tick
+	worker := NewRetentionWorker(store, 24*time.Hour, time.Hour)
+	worker.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := store.Query(ctx, "london", now.Add(-100*time.Hour), now)
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		if len(got) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d observations left, want only the one inside the retention period", len(got))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	worker.Stop()
+}
@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies spans created by this service
+const instrumentationName = "github.com/ANAS727189/weather-project"
+
+// Tracer returns the tracer used for spans across handlers, services and upstream calls
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Option customises Setup
+type Option func(*options)
+
+type options struct {
+	writer io.Writer
+}
+
+// WithWriter sends spans of the stdout exporter to w instead of standard output
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.writer = w
+	}
+}
+
+// Setup installs the global tracer provider and W3C trace-context propagator
+// when tracing is enabled; otherwise both stay no-ops. Baggage is not
+// propagated since it can carry arbitrary client data. The returned function
+// flushes and stops the exporter.
+func Setup(ctx context.Context, cfg config.TracingConfig, opts ...Option) (func(context.Context) error, error) {
+	if !cfg.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	o := options{writer: os.Stdout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	exporter, err := newExporter(ctx, cfg, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %v", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg config.TracingConfig, o options) (sdktrace.SpanExporter, error) {
+	switch strings.ToLower(cfg.Exporter) {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(o.writer))
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	return nil, fmt.Errorf("unknown exporter %q", cfg.Exporter)
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response formats that can be negotiated with Accept or ?format=
+const (
+	FormatJSON    = "json"
+	FormatCSV     = "csv"
+	FormatXML     = "xml"
+	FormatNDJSON  = "ndjson"
+	FormatGeoJSON = "geojson"
+)
+
+// formatContentTypes maps each format to the Content-Type it is served with
+var formatContentTypes = map[string]string{
+	FormatJSON:    "application/json",
+	FormatCSV:     "text/csv; charset=utf-8",
+	FormatXML:     "application/xml",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatGeoJSON: "application/geo+json",
+}
+
+// mediaTypeFormats maps Accept media types, including common aliases, to formats
+var mediaTypeFormats = map[string]string{
+	"application/json":     FormatJSON,
+	"text/csv":             FormatCSV,
+	"application/xml":      FormatXML,
+	"text/xml":             FormatXML,
+	"application/x-ndjson": FormatNDJSON,
+	"application/ndjson":   FormatNDJSON,
+	"application/jsonl":    FormatNDJSON,
+	"application/geo+json": FormatGeoJSON,
+}
+
+// NegotiateFormat picks the response format for r among offered, which lists
+// formats in order of preference with JSON first. ?format= takes precedence
+// over the Accept header; without either the first offered format is used.
+// Browser navigations, whose Accept lists text/html, also get the default so
+// the API stays readable in an address bar.
+func NegotiateFormat(r *http.Request, offered []string) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		for _, candidate := range offered {
+			if candidate == format {
+				return format, nil
+			}
+		}
+		return "", fmt.Errorf("format %q is not available, use one of: %s", format, strings.Join(offered, ", "))
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" || strings.Contains(accept, "text/html") {
+		return offered[0], nil
+	}
+
+	best, bestQ := "", 0.0
+	for _, format := range offered {
+		if q := acceptQuality(accept, format); q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	if best == "" {
+		types := make([]string, len(offered))
+		for i, format := range offered {
+			types[i], _, _ = strings.Cut(formatContentTypes[format], ";")
+		}
+		return "", fmt.Errorf("none of the accepted media types is available, use one of: %s", strings.Join(types, ", "))
+	}
+	return best, nil
+}
+
+// acceptQuality returns the quality an Accept header gives format, taking the
+// most specific matching media range as RFC 9110 requires
+func acceptQuality(accept, format string) float64 {
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		var s int
+		switch {
+		case mediaTypeFormats[mediaType] == format:
+			s = 2
+		case mediaType == "*/*":
+			s = 0
+		case strings.HasSuffix(mediaType, "/*") && strings.HasPrefix(formatContentTypes[format], strings.TrimSuffix(mediaType, "*")):
+			s = 1
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+
+		weight := 1.0
+		if raw, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(raw, 64); err == nil && parsed >= 0 && parsed <= 1 {
+				weight = parsed
+			} else {
+				weight = 0
+			}
+		}
+		if s > specificity || weight > q {
+			q, specificity = weight, s
+		}
+	}
+	return q
+}
+
+type formatKey struct{}
+
+// WithFormat returns a copy of ctx whose success and error responses are
+// written in format
+func WithFormat(ctx context.Context, format string) context.Context {
+	return context.WithValue(ctx, formatKey{}, format)
+}
+
+// FormatFromContext returns the format negotiated for a request, or JSON when none was
+func FormatFromContext(ctx context.Context) string {
+	if format, ok := ctx.Value(formatKey{}).(string); ok {
+		return format
+	}
+	return FormatJSON
+}
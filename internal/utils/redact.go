@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+)
+
+// SensitiveQueryParams are query parameters whose values never appear in logs or errors
+var SensitiveQueryParams = []string{"api_key", "apikey", "appid", "token", "access_token"}
+
+// RedactQuery masks sensitive parameters in query and reports whether any were found
+func RedactQuery(query url.Values) bool {
+	redacted := false
+	for _, param := range SensitiveQueryParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+			redacted = true
+		}
+	}
+	return redacted
+}
+
+// RedactURL returns raw with sensitive query parameters masked
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Unparseable URLs may still carry a key, so drop the query entirely
+		if i := strings.IndexByte(raw, '?'); i >= 0 {
+			return raw[:i] + "?REDACTED"
+		}
+		return raw
+	}
+
+	query := u.Query()
+	if !RedactQuery(query) {
+		return raw
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
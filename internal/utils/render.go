@@ -0,0 +1,320 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tabular is implemented by responses with a natural list of rows, such as
+// forecast slots or history buckets. CSV and NDJSON output contain one row
+// per record; other responses are written as a single row.
+type Tabular interface {
+	Records() interface{}
+}
+
+// GeoJSONer is implemented by responses that can be drawn on a map
+type GeoJSONer interface {
+	GeoJSON() interface{}
+}
+
+// render encodes data in format. CSV, XML and NDJSON are derived from the
+// JSON encoding, so they use the same field names and values as JSON.
+func render(format string, data interface{}) ([]byte, error) {
+	switch format {
+	case FormatGeoJSON:
+		geo, ok := data.(GeoJSONer)
+		if !ok {
+			return nil, fmt.Errorf("%T has no GeoJSON representation", data)
+		}
+		data = geo.GeoJSON()
+	case FormatCSV:
+		return renderCSV(records(data))
+	case FormatNDJSON:
+		return renderNDJSON(records(data))
+	case FormatXML:
+		return renderXML(data)
+	}
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(body, '\n'), nil
+}
+
+// records returns the rows of data as decoded JSON values
+func records(data interface{}) ([]interface{}, error) {
+	if tabular, ok := data.(Tabular); ok {
+		data = tabular.Records()
+	}
+	value, err := decodeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	if rows, ok := value.([]interface{}); ok {
+		return rows, nil
+	}
+	return []interface{}{value}, nil
+}
+
+// renderCSV writes one line per record with nested fields flattened into
+// dotted columns such as main.temp or weather.0.description. The header is
+// the union of all columns in order of first appearance.
+func renderCSV(rows []interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	flattened := make([]map[string]string, len(rows))
+	for i, row := range rows {
+		flattened[i] = make(map[string]string)
+		flatten("", row, func(column, value string) {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+			flattened[i][column] = value
+		})
+	}
+
+	var buf bytes.Buffer
+	if len(columns) == 0 {
+		return buf.Bytes(), nil
+	}
+	w := csv.NewWriter(&buf)
+	w.Write(columns)
+	line := make([]string, len(columns))
+	for _, row := range flattened {
+		for i, column := range columns {
+			line[i] = row[column]
+		}
+		w.Write(line)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// renderNDJSON writes one JSON document per record and line
+func renderNDJSON(rows []interface{}, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for _, row := range rows {
+		if err := writeOrderedJSON(&buf, row); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// renderXML writes data under a <response> root. Object fields become
+// elements named after their JSON keys and array elements become <item>s.
+func renderXML(data interface{}) ([]byte, error) {
+	value, err := decodeOrdered(data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXML(enc, "response", value); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// field is a member of a decoded JSON object
+type field struct {
+	key   string
+	value interface{}
+}
+
+// decodeOrdered round-trips data through JSON into []field for objects, so
+// key order is kept, []interface{} for arrays and json.Number, string, bool
+// or nil for scalars
+func decodeOrdered(data interface{}) (interface{}, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	return decodeValue(dec)
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		object := []field{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, field{key: key.(string), value: value})
+		}
+		_, err := dec.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for dec.More() {
+			value, err := decodeValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err := dec.Token()
+		return array, err
+	}
+	return tok, nil
+}
+
+// flatten calls emit for every scalar in value, naming it by its dotted path
+func flatten(prefix string, value interface{}, emit func(column, value string)) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+	switch v := value.(type) {
+	case []field:
+		for _, f := range v {
+			flatten(join(f.key), f.value, emit)
+		}
+	case []interface{}:
+		for i, element := range v {
+			flatten(join(strconv.Itoa(i)), element, emit)
+		}
+	default:
+		emit(prefix, scalarString(v))
+	}
+}
+
+// writeOrderedJSON encodes a decoded value back to compact JSON in its original key order
+func writeOrderedJSON(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case []field:
+		buf.WriteByte('{')
+		for i, f := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(f.key)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := writeOrderedJSON(buf, f.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, element := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrderedJSON(buf, element); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		scalar, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(scalar)
+	}
+	return nil
+}
+
+// encodeXML writes value as an element called name
+func encodeXML(enc *xml.Encoder, name string, value interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: xmlName(name)}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case []field:
+		for _, f := range v {
+			if err := encodeXML(enc, f.key, f.value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, element := range v {
+			if err := encodeXML(enc, "item", element); err != nil {
+				return err
+			}
+		}
+	default:
+		if text := scalarString(v); text != "" {
+			if err := enc.EncodeToken(xml.CharData(text)); err != nil {
+				return err
+			}
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName makes a JSON key usable as an element name. Characters that may not
+// appear in a name become underscores, and keys such as "3h" that do not start
+// with a letter or underscore get a leading underscore.
+func xmlName(key string) string {
+	if key == "" {
+		return "_"
+	}
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			b.WriteRune(r)
+		case r == '-' || r == '.' || unicode.IsDigit(r):
+			if i == 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// scalarString formats a decoded JSON scalar as text; null becomes empty
+func scalarString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/models"
+)
+
+// checkGeoJSON fails t unless raw is a GeoJSON FeatureCollection as RFC 7946
+// defines it, and returns its features
+func checkGeoJSON(t *testing.T, raw []byte) []map[string]json.RawMessage {
+	t.Helper()
+
+	var collection map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &collection); err != nil {
+		t.Fatalf("not a JSON object: %v", err)
+	}
+	checkType(t, collection, "FeatureCollection")
+	// RFC 7946 removed named coordinate reference systems; positions are WGS 84
+	if _, ok := collection["crs"]; ok {
+		t.Error("collection has a crs member")
+	}
+
+	var features []map[string]json.RawMessage
+	if err := json.Unmarshal(collection["features"], &features); err != nil || features == nil {
+		t.Fatalf("features is not an array: %s", collection["features"])
+	}
+	for _, feature := range features {
+		checkType(t, feature, "Feature")
+		if props, ok := feature["properties"]; !ok || (props[0] != '{' && string(props) != "null") {
+			t.Errorf("feature properties must be an object or null, got %s", props)
+		}
+
+		var geometry map[string]json.RawMessage
+		if err := json.Unmarshal(feature["geometry"], &geometry); err != nil {
+			t.Fatalf("feature geometry is not an object: %s", feature["geometry"])
+		}
+		var kind string
+		json.Unmarshal(geometry["type"], &kind)
+		switch kind {
+		case "Point":
+			var position []float64
+			if err := json.Unmarshal(geometry["coordinates"], &position); err != nil {
+				t.Fatalf("Point coordinates are not a position: %s", geometry["coordinates"])
+			}
+			checkPosition(t, position)
+		case "Polygon":
+			var rings [][][]float64
+			if err := json.Unmarshal(geometry["coordinates"], &rings); err != nil {
+				t.Fatalf("Polygon coordinates are not linear rings: %s", geometry["coordinates"])
+			}
+			checkPolygon(t, rings)
+		case "MultiPolygon":
+			var polygons [][][][]float64
+			if err := json.Unmarshal(geometry["coordinates"], &polygons); err != nil || len(polygons) == 0 {
+				t.Fatalf("MultiPolygon coordinates are not polygons: %s", geometry["coordinates"])
+			}
+			for _, rings := range polygons {
+				checkPolygon(t, rings)
+			}
+		default:
+			t.Errorf("unexpected geometry type %q", kind)
+		}
+	}
+	return features
+}
+
+func checkType(t *testing.T, object map[string]json.RawMessage, want string) {
+	t.Helper()
+	var kind string
+	if err := json.Unmarshal(object["type"], &kind); err != nil || kind != want {
+		t.Errorf("type = %s, want %q", object["type"], want)
+	}
+}
+
+// checkPolygon checks that a polygon has closed linear rings of at least four
+// positions, with the exterior ring counterclockwise and holes clockwise
+func checkPolygon(t *testing.T, rings [][][]float64) {
+	t.Helper()
+	if len(rings) == 0 {
+		t.Fatal("polygon has no exterior ring")
+	}
+	for i, ring := range rings {
+		if len(ring) < 4 {
+			t.Fatalf("ring %v has fewer than four positions", ring)
+		}
+		for _, position := range ring {
+			checkPosition(t, position)
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			t.Errorf("ring %v is not closed", ring)
+		}
+		var area float64
+		for j := 0; j+1 < len(ring); j++ {
+			area += ring[j][0]*ring[j+1][1] - ring[j+1][0]*ring[j][1]
+		}
+		if i == 0 && area <= 0 {
+			t.Errorf("exterior ring %v is not counterclockwise", ring)
+		}
+		if i > 0 && area >= 0 {
+			t.Errorf("hole %v is not clockwise", ring)
+		}
+	}
+}
+
+// checkPosition checks a [longitude, latitude] or [longitude, latitude, altitude] position
+func checkPosition(t *testing.T, position []float64) {
+	t.Helper()
+	if len(position) < 2 || len(position) > 3 {
+		t.Fatalf("position %v must have two or three elements", position)
+	}
+	if position[0] < -180 || position[0] > 180 || position[1] < -90 || position[1] > 90 {
+		t.Errorf("position %v is not [longitude, latitude]", position)
+	}
+}
+
+func TestWriteSuccessResponseGeoJSON(t *testing.T) {
+	data := &models.WeatherData{Name: "London", FetchedAt: time.Now()}
+	data.Coord.Lon, data.Coord.Lat = -0.1257, 51.5085
+	data.Main.Temp = 15.67
+	data.Sys.Country = "GB"
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/weather/London?format=geojson", nil)
+	req = req.WithContext(WithFormat(req.Context(), FormatGeoJSON))
+	rec := httptest.NewRecorder()
+	WriteSuccessResponse(rec, req, data)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/geo+json" {
+		t.Errorf("Content-Type = %q, want application/geo+json", got)
+	}
+
+	features := checkGeoJSON(t, rec.Body.Bytes())
+	if len(features) != 1 {
+		t.Fatalf("got %d features, want 1", len(features))
+	}
+	var geometry struct {
+		Coordinates []float64 `json:"coordinates"`
+	}
+	json.Unmarshal(features[0]["geometry"], &geometry)
+	if geometry.Coordinates[0] != -0.1257 || geometry.Coordinates[1] != 51.5085 {
+		t.Errorf("coordinates = %v, want longitude first", geometry.Coordinates)
+	}
+	var properties models.Observation
+	if err := json.Unmarshal(features[0]["properties"], &properties); err != nil {
+		t.Fatalf("properties: %v", err)
+	}
+	if properties.Location != "city:london" || properties.Name != "London" || properties.Temp != 15.67 {
+		t.Errorf("properties = %+v", properties)
+	}
+}
+
+func TestGeoJSONAlerts(t *testing.T) {
+	data := &models.AlertsData{
+		Lat: 51.5085,
+		Lon: -0.1257,
+		Alerts: []models.WeatherAlert{
+			{Event: "Fog"},
+			// Clockwise and unclosed, as some sources send them
+			{Event: "Wind", Polygons: [][][][]float64{{{{0, 50}, {0, 51}, {1, 51}, {1, 50}}}}},
+			{Event: "Flood", Polygons: [][][][]float64{
+				{
+					{{-1, 50}, {-1, 52}, {1, 52}, {1, 50}, {-1, 50}},
+					{{-0.5, 50.5}, {0.5, 50.5}, {0.5, 51.5}, {-0.5, 51.5}},
+				},
+				{{{2, 50}, {3, 50}, {3, 51}, {2, 50}}},
+				// Too few positions to enclose an area
+				{{{4, 50}, {5, 50}}},
+			}},
+			{Event: "Heat", Polygons: [][][][]float64{{{{4, 50}, {5, 50}, {4, 50}}}}},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/alerts/London?format=geojson", nil)
+	req = req.WithContext(WithFormat(req.Context(), FormatGeoJSON))
+	rec := httptest.NewRecorder()
+	WriteSuccessResponse(rec, req, data)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body: %s", rec.Code, rec.Body)
+	}
+	features := checkGeoJSON(t, rec.Body.Bytes())
+	want := []struct {
+		event    string
+		geometry string
+	}{
+		{"Fog", "Point"},
+		{"Wind", "Polygon"},
+		{"Flood", "MultiPolygon"},
+		{"Heat", "Point"},
+	}
+	if len(features) != len(want) {
+		t.Fatalf("got %d features, want one per alert", len(features))
+	}
+	for i, w := range want {
+		var geometry struct {
+			Type string `json:"type"`
+		}
+		var properties map[string]interface{}
+		json.Unmarshal(features[i]["geometry"], &geometry)
+		json.Unmarshal(features[i]["properties"], &properties)
+		if geometry.Type != w.geometry || properties["event"] != w.event {
+			t.Errorf("feature %d = %s with event %v, want %s for %s", i, geometry.Type, properties["event"], w.geometry, w.event)
+		}
+		if _, ok := properties["polygons"]; ok {
+			t.Errorf("feature %d repeats its area in the properties", i)
+		}
+	}
+}
+
+func TestGeoJSONBatch(t *testing.T) {
+	data := &models.BatchWeatherResponse{
+		Results: []models.BatchWeatherResult{
+			{City: "London", Weather: &models.Observation{Location: "city:london", Lat: 51.5085, Lon: -0.1257}},
+			{City: "Atlantis", Error: "City 'Atlantis' not found"},
+			{City: "Paris", Weather: &models.Observation{Location: "city:paris", Lat: 48.8534, Lon: 2.3488}},
+		},
+		Count: 3,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/weather?cities=London,Atlantis,Paris&format=geojson", nil)
+	req = req.WithContext(WithFormat(req.Context(), FormatGeoJSON))
+	rec := httptest.NewRecorder()
+	WriteSuccessResponse(rec, req, data)
+
+	features := checkGeoJSON(t, rec.Body.Bytes())
+	if len(features) != 2 {
+		t.Fatalf("got %d features, want one per city with weather", len(features))
+	}
+	for i, want := range []string{"city:london", "city:paris"} {
+		var properties models.Observation
+		if err := json.Unmarshal(features[i]["properties"], &properties); err != nil || properties.Location != want {
+			t.Errorf("feature %d properties = %s, want %s", i, features[i]["properties"], want)
+		}
+	}
+}
+
+func TestGeoJSONErrors(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/forecast/London?format=geojson", nil)
+	req = req.WithContext(WithFormat(req.Context(), FormatGeoJSON))
+
+	// Responses without a map representation cannot be written as GeoJSON
+	rec := httptest.NewRecorder()
+	WriteSuccessResponse(rec, req, &models.ForecastData{})
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+
+	// GeoJSON has no error representation, so errors are plain JSON
+	rec = httptest.NewRecorder()
+	WriteErrorResponse(rec, req, "City not found", http.StatusNotFound)
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("error Content-Type = %q, want application/json", got)
+	}
+	var body models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Status != http.StatusNotFound {
+		t.Errorf("error body = %s", rec.Body)
+	}
+}
+
+func TestXMLName(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"temp", "temp"},
+		{"feels_like", "feels_like"},
+		{"_private", "_private"},
+		{"3h", "_3h"},
+		{"-x", "_-x"},
+		{"main.temp", "main.temp"},
+		{"a b", "a_b"},
+		{"ns:name", "ns_name"},
+		{"weather<1>", "weather_1_"},
+		{"température", "température"},
+		{"", "_"},
+	}
+	for _, tt := range tests {
+		if got := xmlName(tt.key); got != tt.want {
+			t.Errorf("xmlName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRenderXMLIsWellFormed(t *testing.T) {
+	data := map[string]interface{}{
+		"3h":        1.5,
+		"a b":       "spaces",
+		"x<y>&z":    "markup",
+		"key:colon": []interface{}{"one", "two"},
+	}
+
+	out, err := render(FormatXML, data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	dec := xml.NewDecoder(strings.NewReader(string(out)))
+	for {
+		if _, err := dec.Token(); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("malformed XML %s: %v", out, err)
+		}
+	}
+}
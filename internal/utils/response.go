@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ANAS727189/weather-project/internal/models"
 )
@@ -14,17 +19,102 @@ func WriteJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 	json.NewEncoder(w).Encode(data)
 }
 
-// WriteErrorResponse writes an error response with proper structure
-func WriteErrorResponse(w http.ResponseWriter, message string, statusCode int) {
+// WriteErrorResponse writes an error response with proper structure, in the
+// format negotiated for r. GeoJSON has no error representation, so errors
+// for GeoJSON requests are written as JSON.
+func WriteErrorResponse(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
 	response := models.ErrorResponse{
 		Error:   http.StatusText(statusCode),
 		Message: message,
 		Status:  statusCode,
 	}
-	WriteJSONResponse(w, statusCode, response)
+	format := FormatFromContext(r.Context())
+	if format == FormatJSON || format == FormatGeoJSON {
+		WriteJSONResponse(w, statusCode, response)
+		return
+	}
+	body, err := render(format, response)
+	if err != nil {
+		WriteJSONResponse(w, statusCode, response)
+		return
+	}
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// WriteSuccessResponse writes a success response in the format negotiated
+// for r. Responses to GET requests carry a strong ETag computed from the
+// body, and conditional requests whose If-None-Match or If-Modified-Since
+// still match are answered with 304 Not Modified. Last-Modified is taken
+// from the headers set by SetCacheHeaders.
+func WriteSuccessResponse(w http.ResponseWriter, r *http.Request, data interface{}) {
+	format := FormatFromContext(r.Context())
+	body, err := render(format, data)
+	if err != nil {
+		WriteErrorResponse(w, r, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+
+		if notModified(r, etag, w.Header().Get("Last-Modified")) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
 }
 
-// WriteSuccessResponse writes a success response
-func WriteSuccessResponse(w http.ResponseWriter, data interface{}) {
-	WriteJSONResponse(w, http.StatusOK, data)
+// SetCacheHeaders sets Last-Modified and a Cache-Control max-age for a
+// response that stays fresh for maxAge. Responses without a positive maxAge,
+// such as uncached or stale data, must be revalidated on every use.
+func SetCacheHeaders(w http.ResponseWriter, lastModified time.Time, maxAge time.Duration) {
+	if !lastModified.IsZero() {
+		// A future Last-Modified is invalid, e.g. when the upstream clock runs ahead
+		if now := time.Now(); lastModified.After(now) {
+			lastModified = now
+		}
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if seconds := int(maxAge.Seconds()); seconds > 0 {
+		w.Header().Set("Cache-Control", "max-age="+strconv.Itoa(seconds))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+}
+
+// notModified evaluates the request's preconditions against the response
+// validators. If-Modified-Since is ignored when If-None-Match is present.
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// If-None-Match uses weak comparison
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims := r.Header.Get("If-Modified-Since")
+	if ims == "" || lastModified == "" {
+		return false
+	}
+	since, err := http.ParseTime(ims)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
 }
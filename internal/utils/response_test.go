@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testLastModified = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// serve writes data with WriteSuccessResponse, last modified at testLastModified
+func serve(t *testing.T, method string, header http.Header, data interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, "/api/v1/weather/London", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	SetCacheHeaders(rec, testLastModified, 5*time.Minute)
+	WriteSuccessResponse(rec, req, data)
+	return rec
+}
+
+func TestWriteSuccessResponseValidators(t *testing.T) {
+	rec := serve(t, http.MethodGet, nil, map[string]int{"temp": 15})
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || len(etag) != 34 {
+		t.Errorf("ETag = %q, want a strong 32 hex digit tag", etag)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "max-age=300" {
+		t.Errorf("Cache-Control = %q, want max-age=300", got)
+	}
+
+	if again := serve(t, http.MethodGet, nil, map[string]int{"temp": 15}); again.Header().Get("ETag") != etag {
+		t.Error("ETag differs for the same body")
+	}
+	if changed := serve(t, http.MethodGet, nil, map[string]int{"temp": 16}); changed.Header().Get("ETag") == etag {
+		t.Error("ETag is the same for a different body")
+	}
+}
+
+func TestWriteSuccessResponseConditional(t *testing.T) {
+	data := map[string]int{"temp": 15}
+	etag := serve(t, http.MethodGet, nil, data).Header().Get("ETag")
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   int
+	}{
+		{"If-None-Match strong", http.MethodGet, http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"If-None-Match weak", http.MethodGet, http.Header{"If-None-Match": {"W/" + etag}}, http.StatusNotModified},
+		{"If-None-Match list", http.MethodGet, http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		{"If-None-Match star", http.MethodGet, http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		{"If-None-Match stale", http.MethodGet, http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"HEAD If-None-Match", http.MethodHead, http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"If-Modified-Since equal", http.MethodGet, http.Header{"If-Modified-Since": {"Sun, 01 Mar 2026 12:00:00 GMT"}}, http.StatusNotModified},
+		{"If-Modified-Since later", http.MethodGet, http.Header{"If-Modified-Since": {"Mon, 02 Mar 2026 00:00:00 GMT"}}, http.StatusNotModified},
+		{"If-Modified-Since earlier", http.MethodGet, http.Header{"If-Modified-Since": {"Sun, 01 Mar 2026 11:59:59 GMT"}}, http.StatusOK},
+		{"If-Modified-Since invalid", http.MethodGet, http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+		// If-Modified-Since is ignored when If-None-Match is present
+		{"If-None-Match wins", http.MethodGet, http.Header{
+			"If-None-Match":     {`"other"`},
+			"If-Modified-Since": {"Mon, 02 Mar 2026 00:00:00 GMT"},
+		}, http.StatusOK},
+		{"POST ignores validators", http.MethodPost, http.Header{"If-None-Match": {etag}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(t, tt.method, tt.header, data)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusNotModified {
+				return
+			}
+			if rec.Body.Len() != 0 {
+				t.Errorf("304 has a body: %q", rec.Body)
+			}
+			if rec.Header().Get("ETag") != etag || rec.Header().Get("Last-Modified") == "" {
+				t.Errorf("304 lacks validators: %v", rec.Header())
+			}
+			if rec.Header().Get("Content-Type") != "" || rec.Header().Get("Content-Length") != "" {
+				t.Errorf("304 describes a body: %v", rec.Header())
+			}
+		})
+	}
+}
+
+func TestSetCacheHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetCacheHeaders(rec, time.Now().Add(time.Hour), 0)
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache without a max age", got)
+	}
+	modified, err := http.ParseTime(rec.Header().Get("Last-Modified"))
+	if err != nil || modified.After(time.Now()) {
+		t.Errorf("Last-Modified = %q, want it clamped to now", rec.Header().Get("Last-Modified"))
+	}
+
+	rec = httptest.NewRecorder()
+	SetCacheHeaders(rec, time.Time{}, 500*time.Millisecond)
+	if got := rec.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("Last-Modified = %q for a zero time", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache below one second", got)
+	}
+}
@@ -0,0 +1,13 @@
+// Package version holds build metadata injected at link time, e.g.
+//
+//	go build -ldflags "-X github.com/ANAS727189/weather-project/internal/version.Version=1.2.0 \
+//	  -X github.com/ANAS727189/weather-project/internal/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/ANAS727189/weather-project/internal/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+// Build metadata; the defaults identify a development build
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildTime = "unknown"
+)
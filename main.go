@@ -2,19 +2,13 @@ package main
 
 import (
 	"log"
+	"os"
 
-	"github.com/ANAS727189/weather-project/internal/config"
-	"github.com/ANAS727189/weather-project/pkg/server"
+	"github.com/ANAS727189/weather-project/internal/cli"
 )
 
 func main() {
-	// Load configuration
-	cfg, err := config.LoadConfig("")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-	srv := server.NewServer(cfg)
-	if err := srv.Start(); err != nil {
+	if err := cli.Run(os.Args[1:]); err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
@@ -2,8 +2,8 @@ package server
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,12 +12,27 @@ import (
 
 	"github.com/ANAS727189/weather-project/internal/config"
 	"github.com/ANAS727189/weather-project/internal/routes"
+	"github.com/ANAS727189/weather-project/internal/tracing"
+	"github.com/ANAS727189/weather-project/internal/version"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	config     *config.Config
-	httpServer *http.Server
+	config          *config.Config
+	httpServer      *http.Server
+	router          *routes.Router
+	shutdownTracing func(context.Context) error
+
+	// certs is set when serving TLS; redirectServer when plain HTTP is redirected to HTTPS
+	certs          *certReloader
+	redirectServer *http.Server
+
+	// adminServer serves operational endpoints when the admin listener is enabled
+	adminServer *http.Server
+
+	// reload re-reads configuration; watchPath is the config file to watch, if any
+	reload    func() (*config.Config, error)
+	watchPath string
 }
 
 
@@ -28,30 +43,134 @@ func NewServer(cfg *config.Config) *Server {
 }
 
 
+// EnableReload makes the server re-read configuration with load on SIGHUP and,
+// when watchPath is not empty, whenever that file changes
+func (s *Server) EnableReload(load func() (*config.Config, error), watchPath string) {
+	s.reload = load
+	s.watchPath = watchPath
+}
+
 func (s *Server) Start() error {
 
-	router := routes.NewRouter(s.config)
-	handler := router.SetupRoutes()
+	shutdownTracing, err := tracing.Setup(context.Background(), s.config.Tracing)
+	if err != nil {
+		return err
+	}
+	s.shutdownTracing = shutdownTracing
+
+	router, err := routes.NewRouter(s.config)
+	if err != nil {
+		return err
+	}
+	s.router = router
+	handler := s.router.SetupRoutes()
+	s.router.Start()
 
 
 	s.httpServer = &http.Server{
-		Addr:         fmt.Sprintf(":%s", s.config.Server.Port),
+		Addr:         s.config.GetAddress(),
 		Handler:      handler,
 		ReadTimeout:  time.Duration(s.config.Server.ReadTimeout) * time.Second,
 		WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
 	}
 
+	tlsCfg := s.config.Server.TLS
+	if tlsCfg.Enabled {
+		certs, err := newCertReloader(tlsCfg)
+		if err != nil {
+			return err
+		}
+		s.certs = certs
+		s.httpServer.TLSConfig = certs.tlsConfig(tlsCfg)
+
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(tlsCfg.HTTP2)
+		s.httpServer.Protocols = &protocols
+
+		if tlsCfg.RedirectPort != "" {
+			s.redirectServer = &http.Server{
+				Addr:         net.JoinHostPort(s.config.Server.Host, tlsCfg.RedirectPort),
+				Handler:      httpsRedirect(s.config.Server.Port),
+				ReadTimeout:  time.Duration(s.config.Server.ReadTimeout) * time.Second,
+				WriteTimeout: time.Duration(s.config.Server.WriteTimeout) * time.Second,
+			}
+			go func() {
+				slog.Info("redirecting HTTP to HTTPS", "address", s.redirectServer.Addr)
+				if err := s.redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					slog.Error("redirect listener failed to start", "error", err)
+					os.Exit(1)
+				}
+			}()
+		}
+	}
+
+	if s.config.Admin.Enabled {
+		// No write timeout: CPU profiles and traces stream for their requested duration
+		s.adminServer = &http.Server{
+			Addr:        s.config.Admin.Address,
+			Handler:     s.router.SetupAdminRoutes(),
+			ReadTimeout: time.Duration(s.config.Server.ReadTimeout) * time.Second,
+		}
+		go func() {
+			slog.Info("admin listener starting", "address", s.adminServer.Addr, "pprof", s.config.Admin.Pprof)
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("admin listener failed to start", "error", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Weather API server starting on port %s...", s.config.Server.Port)
-		log.Println("Available endpoints:")
-		log.Println("GET /api/v1/health - Health check")
-		log.Println("GET /api/v1/weather/{city} - Current weather")
-		log.Println("GET /api/v1/forecast/{city} - 5-day forecast")
-		log.Println("GET /weather/{city} - Legacy current weather endpoint")
-
-		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+		endpoints := []string{
+			"GET /api/v1/health",
+			"GET /livez",
+			"GET /readyz",
+			"GET /api/v1/weather?cities=a,b",
+			"GET /api/v1/weather/{city}",
+			"GET /api/v1/forecast/{city}",
+			"GET /api/v1/alerts/{city}",
+			"GET /api/v1/history/{city}",
+			"GET /api/v1/stream/weather?cities=a,b",
+			"GET /api/v1/ws",
+		}
+		if !s.config.Admin.Enabled {
+			if s.config.Auth.Enabled {
+				endpoints = append(endpoints,
+					"GET /api/v1/admin/quota",
+					"GET /api/v1/admin/config",
+					"GET /api/v1/admin/breaker",
+					"POST /api/v1/admin/breaker/{open|close|reset}",
+					"GET|DELETE /api/v1/admin/cache",
+					"GET /api/v1/admin/cache/entries",
+					"POST /api/v1/admin/cache/prewarm",
+				)
+				if s.config.Metrics.Enabled {
+					endpoints = append(endpoints, "GET /metrics")
+				}
+			}
+		}
+		endpoints = append(endpoints, "GET /weather/{city}")
+		slog.Info("weather API server starting",
+			"address", s.httpServer.Addr,
+			"tls", tlsCfg.Enabled,
+			"version", version.Version,
+			"commit", version.Commit,
+			"build_time", version.BuildTime,
+			"endpoints", endpoints,
+		)
+
+		var err error
+		if tlsCfg.Enabled {
+			// Certificates come from TLSConfig.GetCertificate
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 	return s.waitForShutdown()
@@ -61,20 +180,126 @@ func (s *Server) Start() error {
 func (s *Server) waitForShutdown() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
 
-	log.Println("Shutting down server...")
+	var hup chan os.Signal
+	var changes, certChanges <-chan struct{}
+	if s.reload != nil || s.certs != nil {
+		hup = make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+	}
+	if s.certs != nil {
+		watcher, err := config.NewWatcher(s.certs.files()...)
+		if err != nil {
+			slog.Error("failed to watch TLS certificates, reload with SIGHUP instead", "error", err)
+		} else {
+			defer watcher.Close()
+			certChanges = watcher.Changes()
+		}
+	}
+	if s.reload != nil && s.watchPath != "" {
+		watcher, err := config.NewWatcher(s.watchPath)
+		if err != nil {
+			slog.Error("failed to watch config file, reload with SIGHUP instead", "path", s.watchPath, "error", err)
+		} else {
+			defer watcher.Close()
+			changes = watcher.Changes()
+		}
+	}
+
+wait:
+	for {
+		select {
+		case <-quit:
+			break wait
+		case <-hup:
+			if s.reload != nil {
+				s.reloadConfig("SIGHUP")
+			}
+			if s.certs != nil {
+				s.reloadCertificates("SIGHUP")
+			}
+		case <-changes:
+			s.reloadConfig("file change")
+		case <-certChanges:
+			s.reloadCertificates("file change")
+		}
+	}
+
+	slog.Info("shutting down server")
 
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	// Long-lived streams and WebSocket connections are not handled by http.Server.Shutdown
+	if err := s.router.Shutdown(ctx); err != nil {
+		slog.Error("failed to stop live connections and background services", "error", err)
+	}
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server forced to shutdown: %v", err)
-		return err
+	if s.redirectServer != nil {
+		if err := s.redirectServer.Shutdown(ctx); err != nil {
+			slog.Error("redirect listener forced to shutdown", "error", err)
+		}
+	}
+
+	shutdownErr := s.httpServer.Shutdown(ctx)
+	if shutdownErr != nil {
+		slog.Error("server forced to shutdown", "error", shutdownErr)
+	}
+
+	// The admin listener stops last so metrics stay scrapeable while requests drain
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			slog.Error("admin listener forced to shutdown", "error", err)
+		}
+	}
+
+	// Storage and the cache close only once no request can use them
+	if err := s.router.Close(); err != nil {
+		slog.Error("failed to close storage and cache", "error", err)
 	}
 
-	log.Println("Server exited")
+	// Flush spans still buffered by the exporter
+	if err := s.shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+
+	if shutdownErr != nil {
+		return shutdownErr
+	}
+	slog.Info("server exited")
 	return nil
 }
+
+// reloadConfig re-reads configuration and applies the settings that can change
+// at runtime. Invalid configuration is rejected and the running one is kept.
+func (s *Server) reloadConfig(trigger string) {
+	next, err := s.reload()
+	if err != nil {
+		slog.Error("config reload rejected, keeping current configuration", "trigger", trigger, "error", err)
+		return
+	}
+
+	merged, restartRequired := config.MergeReloadable(s.config, next)
+	if err := s.router.Reload(merged); err != nil {
+		slog.Error("config reload rejected, keeping current configuration", "trigger", trigger, "error", err)
+		return
+	}
+	s.config = merged
+
+	slog.Info("config reloaded", "trigger", trigger)
+	if len(restartRequired) > 0 {
+		slog.Warn("some config changes require a restart to take effect", "settings", restartRequired)
+	}
+}
+
+// reloadCertificates re-reads the TLS certificate, key and client CAs. New
+// connections use them; established connections are left alone. On error the
+// current certificates stay in use.
+func (s *Server) reloadCertificates(trigger string) {
+	if err := s.certs.load(); err != nil {
+		slog.Error("TLS certificate reload failed, keeping current certificates", "trigger", trigger, "error", err)
+		return
+	}
+	slog.Info("TLS certificates reloaded", "trigger", trigger)
+}
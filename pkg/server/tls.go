@@ -0,0 +1,146 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// strictCipherSuites limits TLS 1.2 to forward-secret AEAD suites; TLS 1.3
+// suites are not configurable and are always secure
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// certReloader serves the certificate and client CA pool most recently read
+// from disk. They are looked up on every handshake, so reloading affects new
+// connections only and never drops established ones.
+type certReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	cert      atomic.Pointer[tls.Certificate]
+	clientCAs atomic.Pointer[x509.CertPool]
+}
+
+// newCertReloader loads the configured certificate, key and client CA file
+func newCertReloader(cfg config.TLSConfig) (*certReloader, error) {
+	r := &certReloader{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+	}
+	if cfg.ClientAuth != "none" {
+		r.clientCAFile = cfg.ClientCAFile
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load re-reads the files; on error the previously loaded ones stay in use
+func (r *certReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+
+	var pool *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.clientCAFile)
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.clientCAs.Store(pool)
+	return nil
+}
+
+// files returns the paths to watch for changes
+func (r *certReloader) files() []string {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// tlsConfig builds the server TLS configuration from cfg, taking certificates
+// and client CAs from r
+func (r *certReloader) tlsConfig(cfg config.TLSConfig) *tls.Config {
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+		NextProtos:     []string{"http/1.1"},
+	}
+	if cfg.HTTP2 {
+		base.NextProtos = []string{"h2", "http/1.1"}
+	}
+	if cfg.MinVersion == "1.3" {
+		base.MinVersion = tls.VersionTLS13
+	}
+	if cfg.CipherPolicy == "strict" {
+		base.CipherSuites = strictCipherSuites
+	}
+
+	switch cfg.ClientAuth {
+	case "optional":
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return base
+	}
+
+	// Hand each handshake a copy holding the current client CA pool
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			conn := base.Clone()
+			conn.ClientCAs = r.clientCAs.Load()
+			return conn, nil
+		},
+	}
+}
+
+// httpsRedirect redirects every request to the same host and path over HTTPS
+// on httpsPort
+func httpsRedirect(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ANAS727189/weather-project/internal/config"
+)
+
+// testCA is a throwaway certificate authority for issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+	pool *x509.CertPool
+}
+
+// newTestCA creates a self-signed CA valid for an hour
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "weather-api test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pool: pool,
+	}
+}
+
+// issue returns the PEM certificate and key of a leaf signed by the CA, for
+// localhost when usage is ExtKeyUsageServerAuth
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("serial: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if usage == x509.ExtKeyUsageServerAuth {
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1)}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile writes data to name in dir and returns its path
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+// newTestTLSServer serves 200 OK over TLS with the configuration built by r
+func newTestTLSServer(t *testing.T, r *certReloader, cfg config.TLSConfig) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	// Rejected handshakes are expected, so keep them out of the test output
+	srv := &http.Server{
+		Handler:  http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		ErrorLog: log.New(io.Discard, "", 0),
+	}
+	go srv.Serve(tls.NewListener(ln, r.tlsConfig(cfg)))
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// servedCommonName returns the common name of the certificate served on a new connection to url
+func servedCommonName(t *testing.T, url string, roots *x509.CertPool) string {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+		DisableKeepAlives: true,
+	}}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	cfg := config.TLSConfig{
+		CertFile:   writeFile(t, dir, "tls.crt", certPEM),
+		KeyFile:    writeFile(t, dir, "tls.key", keyPEM),
+		ClientAuth: "none",
+	}
+
+	r, err := newCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	url := newTestTLSServer(t, r, cfg)
+	if got := servedCommonName(t, url, ca.pool); got != "first" {
+		t.Fatalf("served %q, want the initial certificate", got)
+	}
+
+	// A rewritten certificate is served to new connections after a reload
+	certPEM, keyPEM = ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "tls.crt", certPEM)
+	writeFile(t, dir, "tls.key", keyPEM)
+	if err := r.load(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if got := servedCommonName(t, url, ca.pool); got != "second" {
+		t.Errorf("served %q after reload, want the rewritten certificate", got)
+	}
+
+	// A broken certificate, or one that does not match its key, is rejected
+	// and the last good one stays in use
+	writeFile(t, dir, "tls.crt", []byte("not a certificate"))
+	if err := r.load(); err == nil {
+		t.Error("reload accepted an invalid certificate")
+	}
+	mismatched, _ := ca.issue(t, "third", x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "tls.crt", mismatched)
+	if err := r.load(); err == nil {
+		t.Error("reload accepted a certificate that does not match the key")
+	}
+	if got := servedCommonName(t, url, ca.pool); got != "second" {
+		t.Errorf("served %q after failed reloads, want the previous certificate", got)
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCertPEM, clientKeyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatalf("client key pair: %v", err)
+	}
+	otherCA := newTestCA(t)
+	strangerCertPEM, strangerKeyPEM := otherCA.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	strangerCert, err := tls.X509KeyPair(strangerCertPEM, strangerKeyPEM)
+	if err != nil {
+		t.Fatalf("stranger key pair: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mode   string
+		client []tls.Certificate
+		ok     bool
+	}{
+		{"none without certificate", "none", nil, true},
+		{"optional without certificate", "optional", nil, true},
+		{"optional with trusted certificate", "optional", []tls.Certificate{clientCert}, true},
+		{"optional with untrusted certificate", "optional", []tls.Certificate{strangerCert}, false},
+		{"require without certificate", "require", nil, false},
+		{"require with trusted certificate", "require", []tls.Certificate{clientCert}, true},
+		{"require with untrusted certificate", "require", []tls.Certificate{strangerCert}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.TLSConfig{
+				CertFile:     writeFile(t, dir, "tls.crt", certPEM),
+				KeyFile:      writeFile(t, dir, "tls.key", keyPEM),
+				ClientCAFile: writeFile(t, dir, "ca.crt", ca.pem),
+				ClientAuth:   tt.mode,
+			}
+			r, err := newCertReloader(cfg)
+			if err != nil {
+				t.Fatalf("newCertReloader: %v", err)
+			}
+			url := newTestTLSServer(t, r, cfg)
+
+			client := &http.Client{Transport: &http.Transport{
+				TLSClientConfig:   &tls.Config{RootCAs: ca.pool, Certificates: tt.client},
+				DisableKeepAlives: true,
+			}}
+			resp, err := client.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.ok && err != nil {
+				t.Errorf("request failed: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Errorf("request succeeded with status %d, want the handshake rejected", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	tests := []struct {
+		name string
+		port string
+		host string
+		uri  string
+		want string
+	}{
+		{"custom port", "8443", "weather.example.com", "/api/v1/weather/London?units=metric&lang=en", "https://weather.example.com:8443/api/v1/weather/London?units=metric&lang=en"},
+		{"request port replaced", "8443", "weather.example.com:8080", "/readyz", "https://weather.example.com:8443/readyz"},
+		{"default port omitted", "443", "weather.example.com:80", "/api/v1/forecast/Paris?days=3", "https://weather.example.com/api/v1/forecast/Paris?days=3"},
+		{"escaped path kept", "443", "weather.example.com", "/api/v1/weather/S%C3%A3o%20Paulo", "https://weather.example.com/api/v1/weather/S%C3%A3o%20Paulo"},
+		{"ipv6 with port", "8443", "[2001:db8::1]:8080", "/livez", "https://[2001:db8::1]:8443/livez"},
+		{"ipv6 default port", "443", "[2001:db8::1]", "/livez", "https://[2001:db8::1]/livez"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.uri, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			httpsRedirect(tt.port).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusPermanentRedirect {
+				t.Errorf("status = %d, want 308", rec.Code)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}